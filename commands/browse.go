@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+
+	definederrors "github.com/sohWenMing/aggregator/defined_errors"
+	"github.com/sohWenMing/aggregator/html_rendering"
+	"github.com/sohWenMing/aggregator/internal/database"
+)
+
+const defaultBrowseLimit = 2
+
+type browseOptions struct {
+	limit      int32
+	enclosures bool
+	full       bool
+}
+
+// parses the arguments to browse, in the form of "browse [limit] [--enclosures] [--full]"
+func parseBrowseArgs(args []string, w io.Writer) (options browseOptions, err error) {
+	flagSet := flag.NewFlagSet("browse", flag.ContinueOnError)
+	flagSet.SetOutput(w)
+	enclosures := flagSet.Bool("enclosures", false, "list the media files attached to each post")
+	full := flagSet.Bool("full", false, "show the full content of each post instead of its description")
+
+	positionalArgs, err := parseInterspersedFlags(flagSet, args)
+	if err != nil {
+		return browseOptions{}, err
+	}
+	if len(positionalArgs) > 1 {
+		fmt.Fprintln(w, definederrors.ErrorWrongNumArgs.Error())
+		return browseOptions{}, definederrors.ErrorWrongNumArgs
+	}
+	options = browseOptions{
+		limit:      defaultBrowseLimit,
+		enclosures: *enclosures,
+		full:       *full,
+	}
+	if len(positionalArgs) == 1 {
+		parsedInt, err := strconv.ParseInt(positionalArgs[0], 10, 32)
+		if err != nil {
+			fmt.Fprintf(w, "%s could not be parsed into an acceptable limit integer\n", positionalArgs[0])
+			return browseOptions{}, err
+		}
+		options.limit = int32(parsedInt)
+	}
+	return options, nil
+}
+
+func handlerGetPostForUser(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+	options, err := parseBrowseArgs(cmd.args, w)
+	if err != nil {
+		return err
+	}
+
+	userId := state.Cfg.CurrentUser.ID
+	params := database.GetPostsForUserParams{
+		ID:    userId,
+		Limit: options.limit,
+	}
+	records, err := state.Db.GetPostsForUser(ctx, params)
+	if err != nil {
+		fmt.Fprintln(w, err.Error())
+		return err
+	}
+
+	for _, record := range records {
+		fmt.Fprintln(w, "=======================")
+		fmt.Fprintf(w, "FeedName: %s\n", record.FeedName)
+		fmt.Fprintf(w, "Title: %s\n", record.PostTitle)
+		fmt.Fprintf(w, "FeedName: %s\n", record.PostPublishedOn.Time.UTC())
+		if options.full {
+			fmt.Fprintf(w, "Content:\n%s\n", renderPostContent(record, state.Cfg.GetRenderWidth()))
+		} else {
+			fmt.Fprintf(w, "Content: %s\n", record.PostDescription.String)
+		}
+		if options.enclosures {
+			fmt.Fprintf(w, "PostID: %s\n", record.PostID)
+			printEnclosuresForPost(ctx, record.PostID, w, state)
+		}
+		fmt.Fprintln(w, "=======================")
+	}
+
+	return nil
+}
+
+// renders the html content of the post for the terminal. posts that were saved before content was kept only
+// have their description, which is shown as it is
+func renderPostContent(record database.GetPostsForUserRow, width int) string {
+	if !record.PostContent.Valid {
+		return record.PostDescription.String
+	}
+	rendered, err := html_rendering.Render(record.PostContent.String, width)
+	if err != nil {
+		return record.PostDescription.String
+	}
+	return rendered
+}
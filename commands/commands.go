@@ -1,36 +1,43 @@
 package commands
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
+	"os"
+	"os/signal"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sohWenMing/aggregator/date_parsing"
 	definederrors "github.com/sohWenMing/aggregator/defined_errors"
 	errorutils "github.com/sohWenMing/aggregator/error_utils"
+	"github.com/sohWenMing/aggregator/internal/config"
 	"github.com/sohWenMing/aggregator/internal/database"
 	"github.com/sohWenMing/aggregator/rss_parsing"
 )
 
-type handler func(cmd enteredCommand, w io.Writer, state *database.State) (err error)
+type handler func(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error)
 
 // ############# command struct, used to house all the configured commands with relevant methods ######### //
 type commands struct {
 	commandMap map[string]handler
 }
 
-func (c *commands) ExecCommand(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+func (c *commands) ExecCommand(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
 	handler, ok := c.commandMap[cmd.name]
 	if !ok {
 		return definederrors.ErrorHandlerNotExist
 	}
-	handlerErr := handler(cmd, w, state)
+	handlerErr := handler(ctx, cmd, w, state)
 	if handlerErr != nil {
 		return handlerErr
 	}
@@ -58,7 +65,7 @@ func (c *commands) registerAllHandlersTest(nameToHandlers []nameToHandler) (err
 	return nil
 }
 
-func (c *commands) registerHandler(name string, handler func(cmd enteredCommand, w io.Writer, state *database.State) (err error)) (err error) {
+func (c *commands) registerHandler(name string, handler handler) (err error) {
 	if c.commandMap == nil {
 		return fmt.Errorf("pointer to commandMap is nil pointer %w", definederrors.ErrorNilPointer)
 	}
@@ -72,7 +79,7 @@ func (c *commands) registerHandler(name string, handler func(cmd enteredCommand,
 
 type nameToHandler struct {
 	name    string
-	handler func(cmd enteredCommand, w io.Writer, state *database.State) (err error)
+	handler handler
 }
 
 func initAllNameToHandlers() []nameToHandler {
@@ -84,17 +91,19 @@ func initAllNameToHandlers() []nameToHandler {
 		{"agg", handlerAgg},
 		{"addfeed", middleWareLoggedIn(handlerAddFeed)},
 		{"feeds", handlerGetFeeds},
+		{"feed", handlerFeed},
 		{"follow", middleWareLoggedIn(handlerAddFeedFollow)},
 		{"following", middleWareLoggedIn(handlerGetFeedFollowsForUser)},
 		{"unfollow", middleWareLoggedIn(handlerRemoveFeedFollow)},
 		{"browse", middleWareLoggedIn(handlerGetPostForUser)},
+		{"enclosures", handlerGetEnclosures},
 	}
 	return returnedNameToHandlers
 }
 
-func handlerGetUsers(_ enteredCommand, w io.Writer, state *database.State) (err error) {
+func handlerGetUsers(ctx context.Context, _ enteredCommand, w io.Writer, state *database.State) (err error) {
 
-	users, getUsersErr := state.Db.GetUsers(context.Background())
+	users, getUsersErr := state.Db.GetUsers(ctx)
 	if getUsersErr != nil {
 		isPqErr, pqErr, rawErr := errorutils.UnwrapPqErr(getUsersErr)
 		switch isPqErr {
@@ -117,11 +126,11 @@ func handlerGetUsers(_ enteredCommand, w io.Writer, state *database.State) (err
 	return nil
 }
 
-func handlerLogin(cmd enteredCommand, w io.Writer, state *database.State) (retrieveErr error) {
+func handlerLogin(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (retrieveErr error) {
 	if len(cmd.args) != 1 {
 		return fmt.Errorf("args passed into handlerLogin %v %w", cmd.args, definederrors.ErrorWrongNumArgs)
 	}
-	loggedInUser, err := state.Db.RetrieveUser(context.Background(), cmd.args[0])
+	loggedInUser, err := state.Db.RetrieveUser(ctx, cmd.args[0])
 	if err != nil {
 		fmt.Fprintf(w, "user %s could not be retrieved, user is not logged in\n", cmd.args[0])
 		return fmt.Errorf("user %s could not be retrieved, user is not logged in %w", cmd.args[0], definederrors.ErrorUserNotFound)
@@ -134,7 +143,7 @@ func handlerLogin(cmd enteredCommand, w io.Writer, state *database.State) (retri
 	return nil
 }
 
-func handlerRegisterUser(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+func handlerRegisterUser(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
 	if len(cmd.args) != 1 {
 		return fmt.Errorf("args passed into handlerCreateUser %v %w", cmd.args, definederrors.ErrorWrongNumArgs)
 	}
@@ -144,7 +153,7 @@ func handlerRegisterUser(cmd enteredCommand, w io.Writer, state *database.State)
 		UpdatedAt: time.Now(),
 		Name:      cmd.args[0],
 	}
-	createdUser, createErr := state.Db.CreateUser(context.Background(), params)
+	createdUser, createErr := state.Db.CreateUser(ctx, params)
 
 	if createErr != nil {
 		isPQErr, isUniqueViolation, _, rawErr := database.CheckPqErr(createErr)
@@ -168,132 +177,254 @@ func handlerRegisterUser(cmd enteredCommand, w io.Writer, state *database.State)
 	return nil
 }
 
-func handlerResetDatabase(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
-	state.Db.ResetUsers(context.Background())
+func handlerResetDatabase(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+	state.Db.ResetUsers(ctx)
 	return nil
 }
 
-func handlerAgg(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
-	if len(cmd.args) != 1 {
-		fmt.Fprint(w, definederrors.ErrorWrongNumArgs.Error())
-		return definederrors.ErrorWrongNumArgs
-	}
-
-	timeBetweenReqs, err := time.ParseDuration(cmd.args[0])
+// runs the aggregator until it receives SIGINT or SIGTERM, or with --once until every due feed has been scraped.
+// on a signal no more feeds are claimed, the feeds already being scraped are finished and a summary is printed
+func handlerAgg(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+	options, err := parseAggArgs(cmd.args, w)
 	if err != nil {
 		fmt.Fprint(w, err.Error())
 		return err
 	}
 
-	ticker := time.NewTicker(timeBetweenReqs)
-	for ; ; <-ticker.C {
-		scrapeErr := scrapeFeeds(w, state)
-		if scrapeErr != nil {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	summary := &aggSummary{}
+
+	if options.once {
+		scrapeAllDueFeeds(ctx, w, state, options, summary)
+		summary.print(w)
+		return nil
+	}
+
+	ticker := time.NewTicker(options.timeBetweenReqs)
+	defer ticker.Stop()
+	for {
+		_, scrapeErr := scrapeFeeds(ctx, w, state, options, summary)
+		if scrapeErr != nil && ctx.Err() == nil {
 			fmt.Fprintln(w, scrapeErr.Error())
 		}
+		select {
+		case <-ctx.Done():
+			summary.print(w)
+			return nil
+		case <-ticker.C:
+		}
 	}
 }
 
-func handlerGetPostForUser(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
-	if len(cmd.args) > 1 {
-		fmt.Fprintln(w, definederrors.ErrorWrongNumArgs.Error())
-		return definederrors.ErrorWrongNumArgs
-	}
-	var limit int32
-	switch len(cmd.args) == 0 {
-	case true:
-		limit = 2
-	default:
-		parsedInt, err := strconv.ParseInt(cmd.args[0], 10, 32)
-		if err != nil {
-			fmt.Fprintf(w, "%s could not be parsed into an acceptable limit integer\n", cmd.args[0])
-			return err
+// keeps claiming batches of feeds until there are none left that are due. it also stops once a batch only
+// contains feeds that were already scraped in this run, so a feed that couldn't be rescheduled can't keep
+// the loop going forever
+func scrapeAllDueFeeds(ctx context.Context, w io.Writer, state *database.State, options aggOptions, summary *aggSummary) {
+	seenFeeds := map[uuid.UUID]bool{}
+	for ctx.Err() == nil {
+		claimedFeeds, scrapeErr := scrapeFeeds(ctx, w, state, options, summary)
+		if scrapeErr != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintln(w, scrapeErr.Error())
+			}
+			return
+		}
+		hasNewFeed := false
+		for _, claimedFeed := range claimedFeeds {
+			if !seenFeeds[claimedFeed.ID] {
+				hasNewFeed = true
+				seenFeeds[claimedFeed.ID] = true
+			}
+		}
+		if !hasNewFeed {
+			return
 		}
-		limit = int32(parsedInt)
 	}
+}
 
-	userId := state.Cfg.CurrentUser.ID
-	params := database.GetPostsForUserParams{
-		ID:    userId,
-		Limit: limit,
+// claims the next batch of feeds and then hands them to the workers to be scraped
+func scrapeFeeds(ctx context.Context, w io.Writer, state *database.State, options aggOptions, summary *aggSummary) (claimedFeeds []database.Feed, err error) {
+	feedsToFetch, claimErr := claimNextFeeds(ctx, state, options.batchSize)
+	if claimErr != nil {
+		return nil, fmt.Errorf("error occured when claiming the next feeds to fetch: %w", claimErr)
 	}
-	records, err := state.Db.GetPostsForUser(context.Background(), params)
-	if err != nil {
-		fmt.Fprintln(w, err.Error())
+	scrapeFeedsConcurrently(ctx, feedsToFetch, w, state, options, summary)
+	return feedsToFetch, nil
+}
+
+// selects the next feeds that are due and marks them as fetched in one statement. rows locked by another
+// aggregator's claim are skipped, and claimed feeds have their next fetch pushed back by feedClaimLease, so they
+// stay out of the queue for other aggregators, and for later ticks of this one, while they are being scraped
+func claimNextFeeds(ctx context.Context, state *database.State, batchSize int32) (claimedFeeds []database.Feed, err error) {
+	err = state.RunInTx(ctx, func(queries *database.Queries) error {
+		now := time.Now()
+		params := database.ClaimNextFeedsToFetchParams{
+			UpdatedAt:   now,
+			Limit:       batchSize,
+			NextFetchAt: sql.NullTime{Time: now.Add(feedClaimLease), Valid: true},
+		}
+		claimedFeeds, err = queries.ClaimNextFeedsToFetch(ctx, params)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return claimedFeeds, nil
+}
 
-	for _, record := range records {
-		fmt.Fprintln(w, "=======================")
-		fmt.Fprintf(w, "FeedName: %s\n", record.FeedName)
-		fmt.Fprintf(w, "Title: %s\n", record.PostTitle)
-		fmt.Fprintf(w, "FeedName: %s\n", record.PostPublishedOn.Time.UTC())
-		fmt.Fprintf(w, "Content: %s\n", record.PostDescription)
-		fmt.Fprintln(w, "=======================")
+// fetches the feed and writes its posts as they are decoded from the response. ctx cancels the fetch, but the
+// database writes are not tied to it, so a post that has been decoded is never left half written
+func scrapeFeed(ctx context.Context, feedToFetch database.Feed, w io.Writer, state *database.State, bounds pollBounds) (numPosts int, err error) {
+	cache := cacheHeaders{
+		etag:         feedToFetch.Etag.String,
+		lastModified: feedToFetch.LastModified.String,
+	}
+	writeCtx := context.WithoutCancel(ctx)
+	layoutCounts := map[string]int{}
+	numItems := 0
+	writeItem := func(item rss_parsing.RSSItem) error {
+		layoutName := writeItemToDB(writeCtx, feedToFetch, w, item, state)
+		layoutCounts[layoutName]++
+		numItems++
+		return nil
 	}
 
-	return nil
+	result, err := fetchFeed(ctx, feedToFetch.Url, cache, state, writeItem)
+	if err != nil && ctx.Err() != nil {
+		releaseFeedClaim(writeCtx, feedToFetch, w, state)
+		return numItems, ctx.Err()
+	}
+	if err != nil {
+		if errorutils.CheckErrTypeMatch(err, definederrors.ErrorFeedNotModified) {
+			recordFetchSuccess(writeCtx, feedToFetch, w, result.statusCode, state)
+			scheduleFromStoredHints(writeCtx, feedToFetch, w, state)
+			scheduleNextFetch(writeCtx, feedToFetch, w, bounds, state)
+			fmt.Fprintf(w, "Feed %s has not been modified since it was last fetched\n", feedToFetch.Name)
+			return 0, nil
+		}
+		recordFetchFailure(writeCtx, feedToFetch, w, result.statusCode, err, state)
+		disableIfGone(writeCtx, feedToFetch, w, result.statusCode, state)
+		if errorutils.CheckErrTypeMatch(err, context.DeadlineExceeded) {
+			return numItems, fmt.Errorf("the request to %s timed out", feedToFetch.Url)
+		}
+		return numItems, fmt.Errorf("error occured when fetching feed %s: %w", feedToFetch.Url, err)
+	}
+	recordFetchSuccess(writeCtx, feedToFetch, w, result.statusCode, state)
+	if result.permanentRedirectURL != "" && result.permanentRedirectURL != feedToFetch.Url {
+		updateURLAfterRedirect(writeCtx, feedToFetch, w, result.permanentRedirectURL, state)
+	}
+	saveCacheHeaders(writeCtx, feedToFetch, w, result.cache, state)
+	feed := result.feed
+	saveRefreshHints(writeCtx, feedToFetch, w, feed.RefreshHints(), state)
+	fmt.Fprintf(w, "Feed Name: %s\n", feed.Channel.Title)
+	printDateLayoutCounts(w, layoutCounts, numItems)
+	scheduleNextFetch(writeCtx, feedToFetch, w, bounds, state)
+	return numItems, nil
 }
 
-func scrapeFeeds(w io.Writer, state *database.State) (err error) {
-	feedToFetch, err := state.Db.GetNextFeedToFetch(context.Background())
-	if err != nil {
-		fmt.Println("error happened at feedToFetch")
-		return err
+// writes the posts of a feed that has just been added from the copy that addfeed already fetched and parsed,
+// so they can be browsed straight away instead of once agg gets round to the feed. the feed is recorded as
+// fetched along with the cache headers of the response, so that the next fetch by agg can be a conditional one
+func saveInitialScrape(ctx context.Context, feed database.Feed, w io.Writer, result fetchResult, state *database.State) (numPosts int) {
+	writeCtx := context.WithoutCancel(ctx)
+	layoutCounts := map[string]int{}
+	items := result.feed.Channel.RSSItems
+	for _, item := range items {
+		layoutName := writeItemToDB(writeCtx, feed, w, item, state)
+		layoutCounts[layoutName]++
 	}
-	params := database.MarkFetchedFeedParams{
+
+	markErr := state.Db.MarkFetchedFeed(writeCtx, database.MarkFetchedFeedParams{
 		UpdatedAt: time.Now(),
-		ID:        feedToFetch.ID,
-	}
-	markErr := state.Db.MarkFetchedFeed(context.Background(), params)
+		ID:        feed.ID,
+	})
 	if markErr != nil {
-		return markErr
+		fmt.Fprintln(w, markErr.Error())
+	}
+	recordFetchSuccess(writeCtx, feed, w, result.statusCode, state)
+	saveCacheHeaders(writeCtx, feed, w, result.cache, state)
+	saveRefreshHints(writeCtx, feed, w, result.feed.RefreshHints(), state)
+	printDateLayoutCounts(w, layoutCounts, len(items))
+	scheduleNextFetch(writeCtx, feed, w, pollBounds{defaultMinPollInterval, defaultMaxPollInterval}, state)
+	return len(items)
+}
+
+func saveCacheHeaders(ctx context.Context, feed database.Feed, w io.Writer, cache cacheHeaders, state *database.State) {
+	cacheErr := state.Db.UpdateFeedCacheHeaders(ctx, database.UpdateFeedCacheHeadersParams{
+		Etag:         genNullableString(cache.etag),
+		LastModified: genNullableString(cache.lastModified),
+		UpdatedAt:    time.Now(),
+		ID:           feed.ID,
+	})
+	if cacheErr != nil {
+		fmt.Fprintln(w, cacheErr.Error())
 	}
+}
 
-	feed, err := fetchFeed(feedToFetch.Url, state)
-	if err != nil {
-		if err == context.DeadlineExceeded {
-			return errors.New("the operation timed out")
+// prints how many of the scraped posts matched each date layout, so the number of posts that still have
+// dates that can't be parsed can be tracked
+func printDateLayoutCounts(w io.Writer, layoutCounts map[string]int, numItems int) {
+	if numItems == 0 {
+		return
+	}
+	layoutNames := []string{}
+	for layoutName := range layoutCounts {
+		if layoutName != "" {
+			layoutNames = append(layoutNames, layoutName)
 		}
 	}
-	fmt.Fprintf(w, "Feed Name: %s\n", feed.Channel.Title)
-	items := feed.Channel.RSSItems
-	for _, item := range items {
-		writeItemToDB(feedToFetch, w, item, state)
+	sort.Strings(layoutNames)
+	summaries := []string{}
+	for _, layoutName := range layoutNames {
+		summaries = append(summaries, fmt.Sprintf("%s: %d", layoutName, layoutCounts[layoutName]))
 	}
-	return nil
+	fmt.Fprintf(w, "dates parsed: %d/%d [%s]\n", numItems-layoutCounts[""], numItems, strings.Join(summaries, ", "))
 }
 
-func writeItemToDB(feed database.Feed, w io.Writer, item rss_parsing.RSSItem, state *database.State) {
+// writes the item to the database, returning the name of the layout that its publication date matched, or
+// an empty string if the date could not be parsed
+func writeItemToDB(ctx context.Context, feed database.Feed, w io.Writer, item rss_parsing.RSSItem, state *database.State) (layoutName string) {
+
+	//posts are identified by their guid within a feed, feeds that don't provide one fall back to the link
+	guid := item.GUID
+	if guid == "" {
+		guid = item.Link
+	}
+
+	publishedAt, layoutName := parseDate(item.PubDate)
 
-	params := database.CreatePostParams{
+	params := database.UpsertPostParams{
 		ID:          uuid.New(),
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 		Title:       item.Title,
 		Url:         item.Link,
 		Description: genNullableString(item.Description),
-		PublishedAt: parseDate(item.PubDate),
+		PublishedAt: publishedAt,
 		FeedID:      feed.ID,
+		Guid:        guid,
+		Content:     genNullableString(rss_parsing.SanitizeContent(item.Content, state.Cfg.GetContentPolicy())),
 	}
 
-	_, err := state.Db.CreatePost(context.Background(), params)
+	post, err := state.Db.UpsertPost(ctx, params)
 	if err != nil {
 		isPQErr, isUniqueViolation, pqErr, rawErr := database.CheckPqErr(err)
 
 		if isUniqueViolation {
-			return
+			return layoutName
 		}
 
 		if isPQErr {
 			fmt.Fprintf(w, "PqErrCode: %s\n", pqErr.Code)
 			fmt.Fprintf(w, "PqErrMsg: %s\n", pqErr.Message)
-			return
+			return layoutName
 		}
 		fmt.Fprintln(w, rawErr.Error())
-		return
+		return layoutName
 	}
-
+	writeEnclosuresToDB(ctx, post.ID, w, item.Enclosures, state)
+	return layoutName
 }
 
 func genNullableString(input string) (sqlNullableString sql.NullString) {
@@ -308,19 +439,19 @@ func genNullableString(input string) (sqlNullableString sql.NullString) {
 
 }
 
-func parseDate(timestamp string) (sqlNullableTime sql.NullTime) {
-	parsedDate, err := time.Parse(time.RFC1123Z, timestamp)
+func parseDate(timestamp string) (sqlNullableTime sql.NullTime, layoutName string) {
+	parsedDate, layoutName, err := date_parsing.ParseDate(timestamp)
 	var nullTime sql.NullTime
 	if err != nil {
 		nullTime.Valid = false
-		return nullTime
+		return nullTime, ""
 	}
 	nullTime.Time = parsedDate
 	nullTime.Valid = true
-	return nullTime
+	return nullTime, layoutName
 }
 
-func handlerTest(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+func handlerTest(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
 	for _, arg := range cmd.args {
 		fmt.Fprintln(w, arg)
 	}
@@ -328,19 +459,19 @@ func handlerTest(cmd enteredCommand, w io.Writer, state *database.State) (err er
 }
 
 func middleWareLoggedIn(enteredHandler handler) (returnedHandler handler) {
-	return func(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
-		loggedInUser, err := state.Db.RetrieveUser(context.Background(), state.Cfg.CurrentUserName)
+	return func(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+		loggedInUser, err := state.Db.RetrieveUser(ctx, state.Cfg.CurrentUserName)
 		if err != nil {
 			fmt.Printf("user with username %s could not be found in database", state.Cfg.CurrentUserName)
 			return err
 		}
 		state.Cfg.CurrentUser.ID = loggedInUser.ID
 		state.Cfg.CurrentUser.Name = loggedInUser.Name
-		return enteredHandler(cmd, w, state)
+		return enteredHandler(ctx, cmd, w, state)
 	}
 }
 
-func handlerRemoveFeedFollow(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+func handlerRemoveFeedFollow(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
 	if len(cmd.args) != 1 {
 		return fmt.Errorf("wrong num args passed into handlerRemoveFeedFollow, %v %w", cmd.args, definederrors.ErrorWrongNumArgs)
 	}
@@ -348,7 +479,7 @@ func handlerRemoveFeedFollow(cmd enteredCommand, w io.Writer, state *database.St
 		UserID: state.Cfg.CurrentUser.ID,
 		Url:    cmd.args[0],
 	}
-	deleteErr := state.Db.DeleteFeedFollow(context.Background(), params)
+	deleteErr := state.Db.DeleteFeedFollow(ctx, params)
 	if deleteErr != nil {
 		return fmt.Errorf("error occured when attempting to remove feed follow")
 	}
@@ -356,17 +487,25 @@ func handlerRemoveFeedFollow(cmd enteredCommand, w io.Writer, state *database.St
 
 }
 
-func handlerAddFeed(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
-	if len(cmd.args) != 2 {
+// handles "addfeed [name] <url> [--pick n]". the url can be either a feed or a web page that links to one,
+// see resolveFeedURL. if no name is given, the title of the feed is used
+func handlerAddFeed(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+	flagSet := flag.NewFlagSet("addfeed", flag.ContinueOnError)
+	flagSet.SetOutput(w)
+	pick := flagSet.Int("pick", 0, "which of the feeds found on a web page to subscribe to, numbered from 1")
+	args, err := parseInterspersedFlags(flagSet, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 && len(args) != 2 {
 		return fmt.Errorf("wrong num args passed into handlerAddFeed %v %w", cmd.args, definederrors.ErrorWrongNumArgs)
 	}
-	feedName := cmd.args[0]
-	feedUrl := cmd.args[1]
-	_, fetchFeedErr := fetchFeed(feedUrl, state)
+	inputURL := args[len(args)-1]
+	feedUrl, result, fetchFeedErr := resolveFeedURL(ctx, w, inputURL, *pick, state)
 	if fetchFeedErr != nil {
 		switch errorutils.CheckErrTypeMatch(fetchFeedErr, context.DeadlineExceeded) {
 		case true:
-			fmt.Fprintf(w, "The request to %s timed out", feedUrl)
+			fmt.Fprintf(w, "The request to %s timed out", inputURL)
 			return fetchFeedErr
 		case false:
 			fmt.Fprint(w, fetchFeedErr.Error())
@@ -378,43 +517,92 @@ func handlerAddFeed(cmd enteredCommand, w io.Writer, state *database.State) (err
 	// 	fmt.Fprintf(w, "user %s not found in database\n", state.Cfg.CurrentUserName)
 	// 	return fmt.Errorf("user %s not found in database %w", state.Cfg.CurrentUserName, definederrors.ErrorUserNotFound)
 	// }
-	rssFeed, err := state.Db.CreateFeed(context.Background(),
-		database.CreateFeedParams{
+	channel := result.feed.Channel
+	feedName := strings.TrimSpace(channel.Title)
+	if len(args) == 2 {
+		feedName = args[0]
+	}
+	if feedName == "" {
+		//the feed has no title, so there is nothing better to call it by
+		feedName = feedUrl
+	}
+	//the feed and the follow are created together, so that a failed follow doesn't leave behind a feed that
+	//nobody follows
+	var rssFeed database.Feed
+	var feedFollowRow database.CreateFeedFollowRow
+	isExistingFeed := false
+	txErr := state.RunInTx(ctx, func(queries *database.Queries) error {
+		//nothing is inserted if the url has already been added, including by an addfeed running at the same time
+		rssFeed, err = queries.CreateFeed(ctx,
+			database.CreateFeedParams{
+				ID:          uuid.New(),
+				CreatedAt:   time.Now(),
+				UpdatedAt:   time.Now(),
+				Name:        feedName,
+				Url:         feedUrl,
+				UserID:      state.Cfg.CurrentUser.ID,
+				SiteLink:    genNullableString(channel.Link),
+				Description: genNullableString(strings.TrimSpace(channel.Description)),
+				Language:    genNullableString(channel.Language),
+				ImageUrl:    genNullableString(channel.ImageURL),
+				Generator:   genNullableString(channel.Generator),
+			})
+		feedID := rssFeed.ID
+		if errors.Is(err, sql.ErrNoRows) {
+			//someone else has already added the feed, so it only needs to be followed
+			isExistingFeed = true
+			feedID, err = queries.GetFeedIdByURL(ctx, feedUrl)
+			if err != nil {
+				fmt.Fprint(w, "error occured when looking up the feed in the database")
+				return err
+			}
+		} else if err != nil {
+			fmt.Fprint(w, "error occured when trying to add RssFeed to database")
+			return err
+		}
+
+		params := database.CreateFeedFollowParams{
 			ID:        uuid.New(),
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
-			Name:      feedName,
-			Url:       feedUrl,
 			UserID:    state.Cfg.CurrentUser.ID,
-		})
-	if err != nil {
-		fmt.Fprint(w, "error occured when trying to add RssFeed to database")
-		return err
-	}
-	fmt.Fprintf(w, "rss feed values: %v", rssFeed)
-
-	params := database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    state.Cfg.CurrentUser.ID,
-		FeedID:    rssFeed.ID,
-	}
-
-	_, feedFollowErr := state.Db.CreateFeedFollow(context.Background(), params)
-	if feedFollowErr != nil {
-		fmt.Fprint(w, "error occured when attempting to create feedFollow")
-		return feedFollowErr
-	}
+			FeedID:    feedID,
+		}
+		feedFollowRow, err = queries.CreateFeedFollow(ctx, params)
+		if err != nil {
+			_, isUniqueViolation, _, _ := database.CheckPqErr(err)
+			if isUniqueViolation {
+				fmt.Fprintf(w, "you are already following %s\n", feedUrl)
+				return fmt.Errorf("%s %w", feedUrl, definederrors.ErrorAlreadyFollowing)
+			}
+			fmt.Fprint(w, "error occured when attempting to create feedFollow")
+			return err
+		}
+		return nil
+	})
+	if txErr != nil {
+		return txErr
+	}
+	if isExistingFeed {
+		fmt.Fprintf(w, "%s has already been added, %s is now following %s\n", feedUrl, feedFollowRow.UserName, feedFollowRow.FeedName)
+		return nil
+	}
+	fmt.Fprintf(w, "rss feed values: %v\n", rssFeed)
+	numPosts := saveInitialScrape(ctx, rssFeed, w, result, state)
+	fmt.Fprintf(w, "saved %d posts from %s\n", numPosts, rssFeed.Name)
 	return nil
 
 }
 
-func handlerGetFeeds(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
-	if len(cmd.args) != 0 {
+func handlerGetFeeds(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+	if len(cmd.args) == 1 && cmd.args[0] == "--errors" {
+		return handlerGetFeedErrors(ctx, w, state)
+	}
+	isVerbose := len(cmd.args) == 1 && cmd.args[0] == "--verbose"
+	if len(cmd.args) != 0 && !isVerbose {
 		return definederrors.ErrorWrongNumArgs
 	}
-	feeds, err := state.Db.GetFeeds(context.Background())
+	feeds, err := state.Db.GetFeeds(ctx)
 	if err != nil {
 		isPqErr, pqErr, rawErr := errorutils.UnwrapPqErr(err)
 		if isPqErr {
@@ -427,18 +615,41 @@ func handlerGetFeeds(cmd enteredCommand, w io.Writer, state *database.State) (er
 	for _, feed := range feeds {
 		feedInfo := fmt.Sprintf("feed name: %s feed url: %s user name: %s\n", feed.Feedname, feed.Feedurl, feed.Username)
 		fmt.Fprint(w, feedInfo)
+		if isVerbose {
+			printFeedMetadata(w, feed)
+		}
 	}
 	return nil
 }
 
-func handlerAddFeedFollow(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+// prints the channel level details that were saved when the feed was added, leaving out the ones the feed
+// didn't have
+func printFeedMetadata(w io.Writer, feed database.GetFeedsRow) {
+	metadata := []struct {
+		label string
+		value sql.NullString
+	}{
+		{"site", feed.SiteLink},
+		{"description", feed.Description},
+		{"language", feed.Language},
+		{"image", feed.ImageUrl},
+		{"generator", feed.Generator},
+	}
+	for _, field := range metadata {
+		if field.value.Valid {
+			fmt.Fprintf(w, "    %s: %s\n", field.label, field.value.String)
+		}
+	}
+}
+
+func handlerAddFeedFollow(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
 
 	if len(cmd.args) != 1 {
 		fmt.Fprint(w, definederrors.ErrorWrongNumArgs.Error())
 		return definederrors.ErrorWrongNumArgs
 	}
 
-	feedId, err := state.Db.GetFeedIdByURL(context.Background(), cmd.args[0])
+	feedId, err := state.Db.GetFeedIdByURL(ctx, cmd.args[0])
 	if err != nil {
 		fmt.Fprintf(w, "feed with url %s could not be found", cmd.args[0])
 		return err
@@ -451,7 +662,7 @@ func handlerAddFeedFollow(cmd enteredCommand, w io.Writer, state *database.State
 		FeedID:    feedId,
 	}
 
-	feedFollowRow, err := state.Db.CreateFeedFollow(context.Background(), params)
+	feedFollowRow, err := state.Db.CreateFeedFollow(ctx, params)
 	if err != nil {
 		fmt.Fprint(w, "error occured when attempting to create feedFollow")
 		return err
@@ -461,13 +672,13 @@ func handlerAddFeedFollow(cmd enteredCommand, w io.Writer, state *database.State
 
 }
 
-func handlerGetFeedFollowsForUser(cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+func handlerGetFeedFollowsForUser(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
 	if len(cmd.args) != 0 {
 		fmt.Fprint(w, definederrors.ErrorWrongNumArgs.Error())
 		return err
 	}
 
-	feeds, err := state.Db.GetFeedFollowForUser(context.Background(), state.Cfg.CurrentUser.ID)
+	feeds, err := state.Db.GetFeedFollowForUser(ctx, state.Cfg.CurrentUser.ID)
 	if err != nil {
 		fmt.Fprintln(w, err.Error())
 		return err
@@ -479,52 +690,184 @@ func handlerGetFeedFollowsForUser(cmd enteredCommand, w io.Writer, state *databa
 	return nil
 }
 
-func fetchFeed(feedURL string, state *database.State) (feed *rss_parsing.RSSFeed, err error) {
+// validators from the last response for a feed, sent back on the next request so that the server can
+// reply with 304 Not Modified instead of the whole body
+type cacheHeaders struct {
+	etag         string
+	lastModified string
+}
+
+// everything that the scraper needs to know about a response. statusCode is set whenever a response was
+// received, even if an error is also returned
+type fetchResult struct {
+	feed                 *rss_parsing.RSSFeed
+	cache                cacheHeaders
+	statusCode           int
+	permanentRedirectURL string
+}
 
-	req, cancel, err := getReqAndCancelFunc(feedURL)
+// returned by makeRSSReq for any response that isn't a 2xx or 304
+type statusCodeError struct {
+	statusCode int
+	status     string
+}
+
+func (s *statusCodeError) Error() string {
+	return fmt.Sprintf("feed request returned status %s", s.status)
+}
+
+func (s *statusCodeError) Unwrap() error {
+	return definederrors.ErrorUnexpectedStatus
+}
+
+// fetches the feed at feedURL. items are passed to onItem one at a time as they're decoded from the response,
+// or collected into result.feed if onItem is nil
+func fetchFeed(ctx context.Context, feedURL string, cache cacheHeaders, state *database.State, onItem func(item rss_parsing.RSSItem) error) (result fetchResult, err error) {
+
+	req, deadline, err := getReqAndDeadline(ctx, feedURL, cache, state.Cfg.GetFetchTimeout())
 	if err != nil {
-		return nil, err
+		return fetchResult{}, err
 	}
-	defer cancel()
-	return makeRSSReq(*state.Client, req)
+	defer deadline.stop()
+	return makeRSSReq(*state.Client, req, deadline, state.Cfg.GetMaxBodyBytes(), onItem)
 }
 
 func testFetchFeed(feedURL string) (feed *rss_parsing.RSSFeed, err error) {
-	req, cancel, err := getReqAndCancelFunc(feedURL)
+	req, deadline, err := getReqAndDeadline(context.Background(), feedURL, cacheHeaders{}, config.DefaultFetchTimeout)
 	if err != nil {
 		return nil, err
 	}
-	defer cancel()
-	return makeRSSReq(http.Client{}, req)
+	defer deadline.stop()
+	client, err := database.NewHTTPClient(&config.Config{})
+	if err != nil {
+		return nil, err
+	}
+	result, err := makeRSSReq(*client, req, deadline, config.DefaultMaxBodyBytes, nil)
+	return result.feed, err
 }
 
-func makeRSSReq(client http.Client, req *http.Request) (*rss_parsing.RSSFeed, error) {
+// fetches and decodes the feed, handing each item to onItem as it's decoded. the time spent in onItem doesn't
+// count towards the deadline, which only covers waiting on the network
+func makeRSSReq(client http.Client, req *http.Request, deadline *fetchDeadline, maxBodyBytes int64, onItem func(item rss_parsing.RSSItem) error) (fetchResult, error) {
+	getPermanentRedirectURL := trackPermanentRedirects(&client)
 	res, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return fetchResult{}, deadline.wrapErr(req.Context(), err)
 	}
 	defer res.Body.Close()
-	resBody, err := io.ReadAll(res.Body)
+	result := fetchResult{
+		statusCode:           res.StatusCode,
+		permanentRedirectURL: getPermanentRedirectURL(),
+	}
+	if res.StatusCode == http.StatusNotModified {
+		return result, definederrors.ErrorFeedNotModified
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return result, &statusCodeError{res.StatusCode, res.Status}
+	}
+	result.cache = cacheHeaders{
+		etag:         res.Header.Get("ETag"),
+		lastModified: res.Header.Get("Last-Modified"),
+	}
+	if res.ContentLength > maxBodyBytes {
+		return result, fmt.Errorf("content length of %d bytes: %w", res.ContentLength, definederrors.ErrorResponseTooLarge)
+	}
+
+	var collectedItems []rss_parsing.RSSItem
+	if onItem == nil {
+		onItem = func(item rss_parsing.RSSItem) error {
+			collectedItems = append(collectedItems, item)
+			return nil
+		}
+	}
+	handleItem := func(item rss_parsing.RSSItem) error {
+		deadline.pause()
+		defer deadline.resume()
+		return onItem(item)
+	}
+
+	body := bufio.NewReader(&maxBytesReader{
+		reader:    io.LimitReader(res.Body, maxBodyBytes+1),
+		remaining: maxBodyBytes,
+	})
+	//only the start of the body is needed to tell a json feed apart from xml
+	bodyStart, _ := body.Peek(512)
+
+	contentType := res.Header.Get("Content-Type")
+	//relative links are resolved against where the feed ended up after any redirects
+	source := rss_parsing.FeedSource{
+		URL:         res.Request.URL.String(),
+		ContentType: contentType,
+	}
+	var rssFeed rss_parsing.RSSFeed
+	switch rss_parsing.IsJSONFeed(contentType, bodyStart) {
+	case true:
+		rssFeed, err = streamJSONFeed(body, source, handleItem)
+	case false:
+		rssFeed, err = rss_parsing.StreamFeed(body, source, handleItem)
+	}
 	if err != nil {
-		return nil, err
+		return result, deadline.wrapErr(req.Context(), err)
+	}
+	rssFeed.Channel.RSSItems = collectedItems
+	result.feed = &rssFeed
+	return result, nil
+}
+
+// json feeds can't be decoded an item at a time, so the body is read in full, still within the size limit,
+// and its items are then passed to onItem
+func streamJSONFeed(body io.Reader, source rss_parsing.FeedSource, onItem func(item rss_parsing.RSSItem) error) (rssFeed rss_parsing.RSSFeed, err error) {
+	resBody, err := io.ReadAll(body)
+	if err != nil {
+		return rss_parsing.RSSFeed{}, err
 	}
-	rssFeed, err := rss_parsing.ParseRSS(resBody)
+	rssFeed, err = rss_parsing.ParseJSONFeed(resBody, source)
 	if err != nil {
-		return nil, err
+		return rss_parsing.RSSFeed{}, err
+	}
+	for _, item := range rssFeed.Channel.RSSItems {
+		if err := onItem(item); err != nil {
+			return rss_parsing.RSSFeed{}, err
+		}
+	}
+	rssFeed.Channel.RSSItems = nil
+	return rssFeed, nil
+}
+
+// maxBytesReader fails with ErrorResponseTooLarge once more than remaining bytes have been read. reader is
+// expected to be limited to one byte past that, so that an oversized body is never read any further
+type maxBytesReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (m *maxBytesReader) Read(p []byte) (n int, err error) {
+	n, err = m.reader.Read(p)
+	if int64(n) > m.remaining {
+		n = int(m.remaining)
+		m.remaining = 0
+		return n, definederrors.ErrorResponseTooLarge
 	}
-	return &rssFeed, nil
+	m.remaining -= int64(n)
+	return n, err
 }
 
-func getReqAndCancelFunc(feedURL string) (*http.Request, context.CancelFunc, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// builds the request for the feed. the returned deadline has to be stopped once the response is done with
+func getReqAndDeadline(ctx context.Context, feedURL string, cache cacheHeaders, timeout time.Duration) (*http.Request, *fetchDeadline, error) {
+	reqCtx, deadline := newFetchDeadline(ctx, timeout)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, feedURL, nil)
 	if err != nil {
-		cancel()
+		deadline.stop()
 		return nil, nil, err
 	}
-	req.Header.Set("user-agent", "gator")
-	return req, cancel, nil
+	if cache.etag != "" {
+		req.Header.Set("If-None-Match", cache.etag)
+	}
+	if cache.lastModified != "" {
+		req.Header.Set("If-Modified-Since", cache.lastModified)
+	}
+	return req, deadline, nil
 }
 
 // called at the main program, used to initialise the commandMap so that it can be written to
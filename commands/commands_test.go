@@ -4,15 +4,25 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"database/sql"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	definederrors "github.com/sohWenMing/aggregator/defined_errors"
 	errorutils "github.com/sohWenMing/aggregator/error_utils"
 	"github.com/sohWenMing/aggregator/internal/config"
 	"github.com/sohWenMing/aggregator/internal/database"
+	"github.com/sohWenMing/aggregator/rss_parsing"
 	testutils "github.com/sohWenMing/aggregator/test_utils"
 )
 
@@ -82,7 +92,7 @@ func TestInitAndExecCommand(t *testing.T) {
 	cmd, err := ParseCommand(args)
 	testutils.AssertNoErr(err, t)
 	buf := bytes.Buffer{}
-	execError := commandsPtr.ExecCommand(cmd, &buf, nil)
+	execError := commandsPtr.ExecCommand(context.Background(), cmd, &buf, nil)
 	testutils.AssertNoErr(execError, t)
 	linesInBuf := []string{}
 	scanner := bufio.NewScanner(&buf)
@@ -107,7 +117,7 @@ func TestExecCommands(t *testing.T) {
 	registerCmd, err := ParseCommand(registerArgs)
 	testutils.AssertNoErr(err, t)
 	registerBuf := bytes.Buffer{}
-	registerExecError := commandsPtr.ExecCommand(registerCmd, &registerBuf, state)
+	registerExecError := commandsPtr.ExecCommand(context.Background(), registerCmd, &registerBuf, state)
 	testutils.AssertNoErr(registerExecError, t)
 
 	//login nindgabeet
@@ -116,7 +126,7 @@ func TestExecCommands(t *testing.T) {
 	testutils.AssertNoErr(err, t)
 	testutils.AssertNoErr(err, t)
 	buf := bytes.Buffer{}
-	execError := commandsPtr.ExecCommand(cmd, &buf, state)
+	execError := commandsPtr.ExecCommand(context.Background(), cmd, &buf, state)
 	testutils.AssertNoErr(execError, t)
 
 	configAfterSetUser, err := config.Read()
@@ -160,7 +170,7 @@ func TestRegisterHandler(t *testing.T) {
 			buf := bytes.Buffer{}
 			cmd, err := ParseCommand(test.args)
 			testutils.AssertNoErr(err, t)
-			execCommandErr := commandsPtr.ExecCommand(cmd, &buf, state)
+			execCommandErr := commandsPtr.ExecCommand(context.Background(), cmd, &buf, state)
 			switch test.isErrExpected {
 			case true:
 				testutils.AssertHasErr(execCommandErr, t)
@@ -226,7 +236,7 @@ func TestLoginHandler(t *testing.T) {
 			case true:
 				cmd, err := ParseCommand(test.args)
 				testutils.AssertNoErr(err, t)
-				execErr := commandsPtr.ExecCommand(cmd, &buf, state)
+				execErr := commandsPtr.ExecCommand(context.Background(), cmd, &buf, state)
 				isErrMatch := errorutils.CheckUnwrappedError(execErr, test.expectedErr)
 				if !isErrMatch {
 					t.Errorf("got: %v\nwant: %v", execErr, test.expectedErr)
@@ -234,11 +244,11 @@ func TestLoginHandler(t *testing.T) {
 			case false:
 				registerCmd, err := ParseCommand(test.registerUserArgs)
 				testutils.AssertNoErr(err, t)
-				execErr := commandsPtr.ExecCommand(registerCmd, &buf, state)
+				execErr := commandsPtr.ExecCommand(context.Background(), registerCmd, &buf, state)
 				testutils.AssertNoErr(execErr, t)
 				loginCmd, err := ParseCommand(test.args)
 				testutils.AssertNoErr(err, t)
-				loginExecErr := commandsPtr.ExecCommand(loginCmd, &buf, state)
+				loginExecErr := commandsPtr.ExecCommand(context.Background(), loginCmd, &buf, state)
 				testutils.AssertNoErr(loginExecErr, t)
 			}
 			linesInBuf := getLinesInBuf(buf)
@@ -267,7 +277,7 @@ func TestGetUsers(t *testing.T) {
 	for _, args := range registerArgs {
 		cmd, err := ParseCommand(args)
 		testutils.AssertNoErr(err, t)
-		execCommandErr := commandsPtr.ExecCommand(cmd, &buf, state)
+		execCommandErr := commandsPtr.ExecCommand(context.Background(), cmd, &buf, state)
 		testutils.AssertNoErr(execCommandErr, t)
 	}
 
@@ -275,7 +285,7 @@ func TestGetUsers(t *testing.T) {
 	testutils.AssertNoErr(err, t)
 
 	gotBuf := bytes.Buffer{}
-	getUsersErr := commandsPtr.ExecCommand(getUsersCmd, &gotBuf, state)
+	getUsersErr := commandsPtr.ExecCommand(context.Background(), getUsersCmd, &gotBuf, state)
 	testutils.AssertNoErr(getUsersErr, t)
 
 	linesInBuf := getLinesInBuf(gotBuf)
@@ -294,7 +304,7 @@ func TestHandlerAgg(t *testing.T) {
 	handlerAggCmd, err := ParseCommand([]string{"test-program", "agg"})
 
 	testutils.AssertNoErr(err, t)
-	aggErr := commandsPtr.ExecCommand(handlerAggCmd, &buf, state)
+	aggErr := commandsPtr.ExecCommand(context.Background(), handlerAggCmd, &buf, state)
 	testutils.AssertNoErr(aggErr, t)
 	if !strings.Contains(buf.String(), "The Zen of Proverbs") {
 		t.Errorf("buffer should have %q written to it\n", "The Zen of Proverbs")
@@ -314,13 +324,13 @@ func TestHandlerAddFeed(t *testing.T) {
 
 	registerBuf := bytes.Buffer{}
 
-	registerErr := commandsPtr.ExecCommand(registerCmd, &registerBuf, state)
+	registerErr := commandsPtr.ExecCommand(context.Background(), registerCmd, &registerBuf, state)
 	testutils.AssertNoErr(registerErr, t)
 
 	addFeedCmd, err := ParseCommand([]string{"test-program", "addfeed", "Hacker News RSS", "https://hnrss.org/newest"})
 	addFeedBuf := bytes.Buffer{}
 	testutils.AssertNoErr(err, t)
-	addFeedErr := commandsPtr.ExecCommand(addFeedCmd, &addFeedBuf, state)
+	addFeedErr := commandsPtr.ExecCommand(context.Background(), addFeedCmd, &addFeedBuf, state)
 	testutils.AssertNoErr(addFeedErr, t)
 
 	gotString := addFeedBuf.String()
@@ -339,25 +349,25 @@ func TestHandlerGetFeeds(t *testing.T) {
 
 	registerBuf := bytes.Buffer{}
 
-	registerErr := commandsPtr.ExecCommand(registerCmd, &registerBuf, state)
+	registerErr := commandsPtr.ExecCommand(context.Background(), registerCmd, &registerBuf, state)
 	testutils.AssertNoErr(registerErr, t)
 
 	addFeedCmd, err := ParseCommand([]string{"test-program", "addfeed", "Hacker News RSS", "https://hnrss.org/newest"})
 	addFeedBuf := bytes.Buffer{}
 	testutils.AssertNoErr(err, t)
-	addFeedErr := commandsPtr.ExecCommand(addFeedCmd, &addFeedBuf, state)
+	addFeedErr := commandsPtr.ExecCommand(context.Background(), addFeedCmd, &addFeedBuf, state)
 	testutils.AssertNoErr(addFeedErr, t)
 
 	addFeedCmd2, err := ParseCommand([]string{"test-program", "addfeed", "Lanes Blog", "https://www.wagslane.dev/index.xml"})
 	addFeedBuf2 := bytes.Buffer{}
 	testutils.AssertNoErr(err, t)
-	addFeedErr2 := commandsPtr.ExecCommand(addFeedCmd2, &addFeedBuf2, state)
+	addFeedErr2 := commandsPtr.ExecCommand(context.Background(), addFeedCmd2, &addFeedBuf2, state)
 	testutils.AssertNoErr(addFeedErr2, t)
 
 	getFeedsCmd, err := ParseCommand([]string{"test-program", "feeds"})
 	feedsBuf := bytes.Buffer{}
 	testutils.AssertNoErr(err, t)
-	getFeedsErr := commandsPtr.ExecCommand(getFeedsCmd, &feedsBuf, state)
+	getFeedsErr := commandsPtr.ExecCommand(context.Background(), getFeedsCmd, &feedsBuf, state)
 	testutils.AssertNoErr(getFeedsErr, t)
 
 	bufStrings := []string{}
@@ -369,6 +379,220 @@ func TestHandlerGetFeeds(t *testing.T) {
 
 }
 
+func TestHandlerAddFeedDefaultName(t *testing.T) {
+	feedBuf, err := os.ReadFile("../rss_parsing/testfile.xml")
+	testutils.AssertNoErr(err, t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(feedBuf)
+	}))
+	defer server.Close()
+
+	commandsPtr, state := initCommandsAndState(t)
+	defer state.Db.ResetUsers(context.Background())
+	defer state.Db.ResetFeeds(context.Background())
+
+	registerCmd, err := ParseCommand([]string{"test-program", "register", "nindgabeet"})
+	testutils.AssertNoErr(err, t)
+	registerErr := commandsPtr.ExecCommand(context.Background(), registerCmd, &bytes.Buffer{}, state)
+	testutils.AssertNoErr(registerErr, t)
+
+	addFeedCmd, err := ParseCommand([]string{"test-program", "addfeed", server.URL})
+	testutils.AssertNoErr(err, t)
+	addFeedErr := commandsPtr.ExecCommand(context.Background(), addFeedCmd, &bytes.Buffer{}, state)
+	testutils.AssertNoErr(addFeedErr, t)
+
+	getFeedsCmd, err := ParseCommand([]string{"test-program", "feeds", "--verbose"})
+	testutils.AssertNoErr(err, t)
+	feedsBuf := bytes.Buffer{}
+	getFeedsErr := commandsPtr.ExecCommand(context.Background(), getFeedsCmd, &feedsBuf, state)
+	testutils.AssertNoErr(getFeedsErr, t)
+
+	gotString := feedsBuf.String()
+	for _, expected := range []string{"feed name: Lane's Blog", "site: https://wagslane.dev/", "language: en-us", "generator: Hugo -- gohugo.io"} {
+		if !strings.Contains(gotString, expected) {
+			t.Errorf("feeds --verbose output %q should contain %q", gotString, expected)
+		}
+	}
+}
+
+func TestHandlerAddFeedInitialScrape(t *testing.T) {
+	feedBuf, err := os.ReadFile("../rss_parsing/testfile.xml")
+	testutils.AssertNoErr(err, t)
+	numRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		numRequests++
+		w.Write(feedBuf)
+	}))
+	defer server.Close()
+
+	commands, state := initCommandsAndState(t)
+	defer state.Db.ResetUsers(context.Background())
+	defer state.Db.ResetFeeds(context.Background())
+
+	registerUser(t, commands, state, "kahya")
+	addFeed(t, commands, state, "Lanes Blog", server.URL)
+	//the posts come from the response that was fetched to validate the feed, rather than a second request
+	testutils.AssertInts(numRequests, 1, t)
+
+	browseCmd, err := ParseCommand([]string{"test-program", "browse", "1"})
+	testutils.AssertNoErr(err, t)
+	browseBuf := bytes.Buffer{}
+	browseErr := commands.ExecCommand(context.Background(), browseCmd, &browseBuf, state)
+	testutils.AssertNoErr(browseErr, t)
+	if !strings.Contains(browseBuf.String(), "The Zen of Proverbs") {
+		t.Errorf("browse output %q should contain the newest post of the feed", browseBuf.String())
+	}
+}
+
+func TestHandlerAddFeedExisting(t *testing.T) {
+	commands, state := initCommandsAndState(t)
+	defer state.Db.ResetUsers(context.Background())
+	defer state.Db.ResetFeeds(context.Background())
+
+	registerUser(t, commands, state, "kahya")
+	addFeed(t, commands, state, "Lanes Blog", "https://www.wagslane.dev/index.xml")
+
+	//adding a feed that already exists follows it instead of failing on the unique url
+	registerUser(t, commands, state, "holgith")
+	addFeed(t, commands, state, "Another Name", "https://www.wagslane.dev/index.xml")
+	bufHolgith := runFollowing(t, commands, state)
+	processBufAndAssertStrings(t, bufHolgith, []string{"Lanes Blog"})
+
+	feeds, err := state.Db.GetFeeds(context.Background())
+	testutils.AssertNoErr(err, t)
+	testutils.AssertInts(len(feeds), 1, t)
+
+	//adding it again when already following it is an error, and doesn't change anything
+	addFeedCmd, err := ParseCommand([]string{"test-program", "addfeed", "https://www.wagslane.dev/index.xml"})
+	testutils.AssertNoErr(err, t)
+	addFeedErr := commands.ExecCommand(context.Background(), addFeedCmd, &bytes.Buffer{}, state)
+	if !errorutils.CheckErrTypeMatch(addFeedErr, definederrors.ErrorAlreadyFollowing) {
+		t.Errorf("expected %v, got %v", definederrors.ErrorAlreadyFollowing, addFeedErr)
+	}
+}
+
+func TestClaimNextFeedsIsDisjoint(t *testing.T) {
+	commands, state := initCommandsAndState(t)
+	defer state.Db.ResetUsers(context.Background())
+	defer state.Db.ResetFeeds(context.Background())
+
+	registerUser(t, commands, state, "kahya")
+	for i := 0; i < 3; i++ {
+		_, err := state.Db.CreateFeed(context.Background(), database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      fmt.Sprintf("feed %d", i),
+			Url:       fmt.Sprintf("https://example.com/%d.xml", i),
+			UserID:    state.Cfg.CurrentUser.ID,
+		})
+		testutils.AssertNoErr(err, t)
+	}
+
+	//feeds that have been claimed are leased, so a second claim straight after only gets the rest
+	firstClaim, err := claimNextFeeds(context.Background(), state, 2)
+	testutils.AssertNoErr(err, t)
+	secondClaim, err := claimNextFeeds(context.Background(), state, 2)
+	testutils.AssertNoErr(err, t)
+	testutils.AssertInts(len(firstClaim), 2, t)
+	testutils.AssertInts(len(secondClaim), 1, t)
+	for _, first := range firstClaim {
+		for _, second := range secondClaim {
+			if first.ID == second.ID {
+				t.Errorf("feed %s was claimed twice", first.Url)
+			}
+		}
+	}
+
+	thirdClaim, err := claimNextFeeds(context.Background(), state, 2)
+	testutils.AssertNoErr(err, t)
+	testutils.AssertInts(len(thirdClaim), 0, t)
+}
+
+func TestClaimNextFeedsAfterBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	commands, state := initCommandsAndState(t)
+	defer state.Db.ResetUsers(context.Background())
+	defer state.Db.ResetFeeds(context.Background())
+
+	registerUser(t, commands, state, "kahya")
+	_, err := state.Db.CreateFeed(context.Background(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      "failing feed",
+		Url:       server.URL,
+		UserID:    state.Cfg.CurrentUser.ID,
+	})
+	testutils.AssertNoErr(err, t)
+
+	claimed, err := claimNextFeeds(context.Background(), state, 1)
+	testutils.AssertNoErr(err, t)
+	testutils.AssertInts(len(claimed), 1, t)
+	_, err = scrapeFeed(context.Background(), claimed[0], &bytes.Buffer{}, state, pollBounds{defaultMinPollInterval, defaultMaxPollInterval})
+	testutils.AssertHasErr(err, t)
+
+	//the backoff replaces the lease, so the feed is due again once the backoff is over rather than the lease
+	claimed, err = claimNextFeeds(context.Background(), state, 1)
+	testutils.AssertNoErr(err, t)
+	testutils.AssertInts(len(claimed), 0, t)
+	afterBackoff := time.Now().Add(getBackoffDuration(1) + time.Minute)
+	claimed, err = state.Db.ClaimNextFeedsToFetch(context.Background(), database.ClaimNextFeedsToFetchParams{
+		UpdatedAt:   afterBackoff,
+		Limit:       1,
+		NextFetchAt: sql.NullTime{Time: afterBackoff.Add(feedClaimLease), Valid: true},
+	})
+	testutils.AssertNoErr(err, t)
+	testutils.AssertInts(len(claimed), 1, t)
+}
+
+func TestHandlerAddFeedConcurrent(t *testing.T) {
+	feedBuf, err := os.ReadFile("../rss_parsing/testfile.xml")
+	testutils.AssertNoErr(err, t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(feedBuf)
+	}))
+	defer server.Close()
+
+	//each user has their own state, as two aggregators would
+	kahyaCommands, kahyaState := initCommandsAndState(t)
+	holgithCommands, holgithState := initCommandsAndState(t)
+	defer kahyaState.Db.ResetUsers(context.Background())
+	defer kahyaState.Db.ResetFeeds(context.Background())
+	registerUser(t, kahyaCommands, kahyaState, "kahya")
+	registerUser(t, holgithCommands, holgithState, "holgith")
+
+	//whichever addfeed loses the race follows the feed the other one added
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, user := range []struct {
+		commands *commands
+		state    *database.State
+	}{{kahyaCommands, kahyaState}, {holgithCommands, holgithState}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addFeedCmd, err := ParseCommand([]string{"test-program", "addfeed", server.URL})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = user.commands.ExecCommand(context.Background(), addFeedCmd, &bytes.Buffer{}, user.state)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		testutils.AssertNoErr(err, t)
+	}
+
+	feeds, err := kahyaState.Db.GetFeeds(context.Background())
+	testutils.AssertNoErr(err, t)
+	testutils.AssertInts(len(feeds), 1, t)
+}
+
 func TestHandlerAddFeedFollow(t *testing.T) {
 
 	/*
@@ -438,6 +662,502 @@ func TestRunTestFetchFeed(t *testing.T) {
 	}
 }
 
+func TestParseAggArgs(t *testing.T) {
+	defaultBounds := pollBounds{defaultMinPollInterval, defaultMaxPollInterval}
+	type testStruct struct {
+		name          string
+		args          []string
+		isErrExpected bool
+		expected      aggOptions
+	}
+
+	tests := []testStruct{
+		{
+			"duration only",
+			[]string{"1m"},
+			false,
+			aggOptions{time.Minute, 1, 1, defaultBounds, false},
+		},
+		{
+			"flags after duration",
+			[]string{"1m", "--workers", "8", "--batch", "50"},
+			false,
+			aggOptions{time.Minute, 8, 50, defaultBounds, false},
+		},
+		{
+			"flags before duration, batch defaults to workers",
+			[]string{"-workers=4", "30s"},
+			false,
+			aggOptions{30 * time.Second, 4, 4, defaultBounds, false},
+		},
+		{
+			"custom poll bounds",
+			[]string{"1m", "--min-interval", "5m", "--max-interval", "6h"},
+			false,
+			aggOptions{time.Minute, 1, 1, pollBounds{5 * time.Minute, 6 * time.Hour}, false},
+		},
+		{
+			"min interval larger than max interval",
+			[]string{"1m", "--min-interval", "2h", "--max-interval", "1h"},
+			true,
+			aggOptions{},
+		},
+		{
+			"once without duration",
+			[]string{"--once", "--workers", "8"},
+			false,
+			aggOptions{0, 8, 8, defaultBounds, true},
+		},
+		{
+			"no duration",
+			[]string{"--workers", "8"},
+			true,
+			aggOptions{},
+		},
+		{
+			"zero workers",
+			[]string{"1m", "--workers", "0"},
+			true,
+			aggOptions{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			got, err := parseAggArgs(test.args, &buf)
+			switch test.isErrExpected {
+			case true:
+				testutils.AssertHasErr(err, t)
+			case false:
+				testutils.AssertNoErr(err, t)
+				if got != test.expected {
+					t.Errorf("got: %+v\nwant: %+v\n", got, test.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestGetAdaptivePollInterval(t *testing.T) {
+	bounds := pollBounds{15 * time.Minute, 24 * time.Hour}
+	type testStruct struct {
+		name                string
+		averagePostInterval time.Duration
+		expected            time.Duration
+	}
+	tests := []testStruct{
+		{"no dated posts", 0, 15 * time.Minute},
+		{"posts every few minutes", 10 * time.Minute, 15 * time.Minute},
+		{"posts every six hours", 6 * time.Hour, 3 * time.Hour},
+		{"posts weekly", 7 * 24 * time.Hour, 24 * time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := getAdaptivePollInterval(test.averagePostInterval, bounds)
+			if got != test.expected {
+				t.Errorf("got: %v\nwant: %v\n", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestMakeRSSReqConditionalGet(t *testing.T) {
+	feedBuf, err := os.ReadFile("../rss_parsing/testfile.xml")
+	testutils.AssertNoErr(err, t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Sun, 08 Jan 2023 00:00:00 GMT")
+		w.Write(feedBuf)
+	}))
+	defer server.Close()
+
+	req, deadline, err := getReqAndDeadline(context.Background(), server.URL, cacheHeaders{}, config.DefaultFetchTimeout)
+	testutils.AssertNoErr(err, t)
+	defer deadline.stop()
+	result, err := makeRSSReq(http.Client{}, req, deadline, config.DefaultMaxBodyBytes, nil)
+	testutils.AssertNoErr(err, t)
+	testutils.AssertStrings(result.feed.Channel.Title, "Lane's Blog", t)
+	testutils.AssertStrings(result.cache.etag, `"v1"`, t)
+	testutils.AssertStrings(result.cache.lastModified, "Sun, 08 Jan 2023 00:00:00 GMT", t)
+
+	cachedReq, cachedDeadline, err := getReqAndDeadline(context.Background(), server.URL, result.cache, config.DefaultFetchTimeout)
+	testutils.AssertNoErr(err, t)
+	defer cachedDeadline.stop()
+	_, err = makeRSSReq(http.Client{}, cachedReq, cachedDeadline, config.DefaultMaxBodyBytes, nil)
+	if !errorutils.CheckErrTypeMatch(err, definederrors.ErrorFeedNotModified) {
+		t.Errorf("got: %v\nwant: %v\n", err, definederrors.ErrorFeedNotModified)
+	}
+}
+
+func TestMakeRSSReqStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	req, deadline, err := getReqAndDeadline(context.Background(), server.URL, cacheHeaders{}, config.DefaultFetchTimeout)
+	testutils.AssertNoErr(err, t)
+	defer deadline.stop()
+	result, err := makeRSSReq(http.Client{}, req, deadline, config.DefaultMaxBodyBytes, nil)
+	if !errorutils.CheckErrTypeMatch(err, definederrors.ErrorUnexpectedStatus) {
+		t.Errorf("got: %v\nwant: %v\n", err, definederrors.ErrorUnexpectedStatus)
+	}
+	testutils.AssertInts(result.statusCode, http.StatusInternalServerError, t)
+}
+
+func TestMakeRSSReqPermanentRedirect(t *testing.T) {
+	feedBuf, err := os.ReadFile("../rss_parsing/testfile.xml")
+	testutils.AssertNoErr(err, t)
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/moved", http.StatusMovedPermanently))
+	mux.Handle("/moved", http.RedirectHandler("/feed", http.StatusPermanentRedirect))
+	mux.Handle("/temporary", http.RedirectHandler("/feed", http.StatusFound))
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(feedBuf)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	type testStruct struct {
+		name        string
+		path        string
+		expectedURL string
+	}
+	tests := []testStruct{
+		{"chain of permanent redirects", "/old", server.URL + "/feed"},
+		{"temporary redirect", "/temporary", ""},
+		{"no redirect", "/feed", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, deadline, err := getReqAndDeadline(context.Background(), server.URL+test.path, cacheHeaders{}, config.DefaultFetchTimeout)
+			testutils.AssertNoErr(err, t)
+			defer deadline.stop()
+			result, err := makeRSSReq(http.Client{}, req, deadline, config.DefaultMaxBodyBytes, nil)
+			testutils.AssertNoErr(err, t)
+			testutils.AssertStrings(result.permanentRedirectURL, test.expectedURL, t)
+		})
+	}
+}
+
+func TestMakeRSSReqMaxBodySize(t *testing.T) {
+	feedBuf, err := os.ReadFile("../rss_parsing/testfile.xml")
+	testutils.AssertNoErr(err, t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(feedBuf)))
+		w.Write(feedBuf)
+	})
+	mux.HandleFunc("/chunked", func(w http.ResponseWriter, r *http.Request) {
+		// flushing before the body is written stops a content length from being sent, like a server streaming its response
+		w.(http.Flusher).Flush()
+		w.Write(feedBuf)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	type testStruct struct {
+		name          string
+		path          string
+		maxBodyBytes  int64
+		isErrExpected bool
+	}
+	tests := []testStruct{
+		{"within limit", "/feed", int64(len(feedBuf)), false},
+		{"over limit with content length", "/feed", int64(len(feedBuf)) - 1, true},
+		// the limit has to cut into the document, a trailing newline after the root element is never read
+		{"over limit without content length", "/chunked", int64(len(feedBuf)) / 2, true},
+		{"within limit without content length", "/chunked", int64(len(feedBuf)), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, deadline, err := getReqAndDeadline(context.Background(), server.URL+test.path, cacheHeaders{}, config.DefaultFetchTimeout)
+			testutils.AssertNoErr(err, t)
+			defer deadline.stop()
+			result, err := makeRSSReq(http.Client{}, req, deadline, test.maxBodyBytes, nil)
+			switch test.isErrExpected {
+			case true:
+				if !errorutils.CheckErrTypeMatch(err, definederrors.ErrorResponseTooLarge) {
+					t.Errorf("got: %v\nwant: %v\n", err, definederrors.ErrorResponseTooLarge)
+				}
+			case false:
+				testutils.AssertNoErr(err, t)
+				testutils.AssertStrings(result.feed.Channel.Title, "Lane's Blog", t)
+			}
+		})
+	}
+}
+
+func TestMakeRSSReqStreamsItems(t *testing.T) {
+	feedBuf, err := os.ReadFile("../rss_parsing/testfile.xml")
+	testutils.AssertNoErr(err, t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(feedBuf)
+	}))
+	defer server.Close()
+
+	req, deadline, err := getReqAndDeadline(context.Background(), server.URL, cacheHeaders{}, config.DefaultFetchTimeout)
+	testutils.AssertNoErr(err, t)
+	defer deadline.stop()
+	links := []string{}
+	result, err := makeRSSReq(http.Client{}, req, deadline, config.DefaultMaxBodyBytes, func(item rss_parsing.RSSItem) error {
+		links = append(links, item.Link)
+		return nil
+	})
+	testutils.AssertNoErr(err, t)
+	// items handed to the callback aren't also collected into the feed
+	testutils.AssertInts(len(result.feed.Channel.RSSItems), 0, t)
+	if len(links) == 0 {
+		t.Fatal("expected items to be passed to the callback")
+	}
+	testutils.AssertStrings(links[0], "https://wagslane.dev/posts/zen-of-proverbs/", t)
+}
+
+func TestMakeRSSReqDeadline(t *testing.T) {
+	feedBuf, err := os.ReadFile("../rss_parsing/testfile.xml")
+	testutils.AssertNoErr(err, t)
+	secondItemEnd := bytes.Index(feedBuf, []byte("</item>"))
+	secondItemEnd += bytes.Index(feedBuf[secondItemEnd+1:], []byte("</item>")) + 1 + len("</item>")
+
+	stalled := make(chan struct{})
+	defer close(stalled)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(feedBuf)
+	})
+	mux.HandleFunc("/stalled", func(w http.ResponseWriter, r *http.Request) {
+		//the first two items are sent, and then the rest of the body never arrives
+		w.Write(feedBuf[:secondItemEnd])
+		w.(http.Flusher).Flush()
+		select {
+		case <-stalled:
+		case <-r.Context().Done():
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	timeout := 200 * time.Millisecond
+	type testStruct struct {
+		name              string
+		path              string
+		writeDelay        time.Duration
+		isTimeoutExpected bool
+		expectedNumItems  int
+	}
+	tests := []testStruct{
+		// writing every item takes longer than the timeout in total, but none of it is spent on the network
+		{"slow writes", "/feed", 20 * time.Millisecond, false, 21},
+		// the items that arrived before the body stalled are written, and the fetch is reported as timed out
+		{"stream stalls after two items", "/stalled", 0, true, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, deadline, err := getReqAndDeadline(context.Background(), server.URL+test.path, cacheHeaders{}, timeout)
+			testutils.AssertNoErr(err, t)
+			defer deadline.stop()
+			numItems := 0
+			_, err = makeRSSReq(http.Client{}, req, deadline, config.DefaultMaxBodyBytes, func(item rss_parsing.RSSItem) error {
+				time.Sleep(test.writeDelay)
+				numItems++
+				return nil
+			})
+			switch test.isTimeoutExpected {
+			case true:
+				if !errorutils.CheckErrTypeMatch(err, context.DeadlineExceeded) {
+					t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+				}
+			case false:
+				testutils.AssertNoErr(err, t)
+			}
+			testutils.AssertInts(numItems, test.expectedNumItems, t)
+		})
+	}
+}
+
+func TestResolveFeedURL(t *testing.T) {
+	feedBuf, err := os.ReadFile("../rss_parsing/testfile.xml")
+	testutils.AssertNoErr(err, t)
+	var feedRequests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		feedRequests.Add(1)
+		w.Write(feedBuf)
+	})
+	mux.HandleFunc("/index.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(feedBuf)
+	})
+	mux.HandleFunc("/linked", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><link rel="alternate" type="application/rss+xml" href="/feed.xml"></head><body></body></html>`))
+	})
+	mux.HandleFunc("/two-links", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head>
+			<link rel="alternate" type="application/rss+xml" title="Posts" href="/feed.xml">
+			<link rel="alternate" type="application/atom+xml" title="Everything" href="/index.xml">
+			</head><body></body></html>`))
+	})
+	mux.HandleFunc("/blog/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>no feed links</title></head><body></body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	state := &database.State{Cfg: &config.Config{}, Client: &http.Client{}}
+
+	type testStruct struct {
+		name          string
+		inputURL      string
+		pick          int
+		expected      string
+		isErrExpected bool
+	}
+	tests := []testStruct{
+		{"url is a feed", server.URL + "/feed.xml", 0, server.URL + "/feed.xml", false},
+		{"page links to a feed", server.URL + "/linked", 0, server.URL + "/feed.xml", false},
+		{"page links to two feeds", server.URL + "/two-links", 0, "", true},
+		{"pick second of two feeds", server.URL + "/two-links", 2, server.URL + "/index.xml", false},
+		{"pick out of range", server.URL + "/two-links", 3, "", true},
+		{"common path is probed", server.URL + "/blog/", 0, server.URL + "/feed.xml", false},
+		{"pick given for a feed", server.URL + "/feed.xml", 1, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			got, result, err := resolveFeedURL(context.Background(), &buf, test.inputURL, test.pick, state)
+			switch test.isErrExpected {
+			case true:
+				testutils.AssertHasErr(err, t)
+			case false:
+				testutils.AssertNoErr(err, t)
+				testutils.AssertStrings(got, test.expected, t)
+				// the feed is parsed along with its items, so that addfeed can use its channel details
+				testutils.AssertStrings(result.feed.Channel.Title, "Lane's Blog", t)
+				if len(result.feed.Channel.RSSItems) == 0 {
+					t.Error("expected the items of the feed to be collected")
+				}
+			}
+		})
+	}
+
+	//a probed feed is kept from the probe rather than being downloaded again once it's chosen
+	feedRequests.Store(0)
+	_, _, err = resolveFeedURL(context.Background(), &bytes.Buffer{}, server.URL+"/blog/", 0, state)
+	testutils.AssertNoErr(err, t)
+	testutils.AssertInts(int(feedRequests.Load()), 1, t)
+}
+
+func TestFindFeedLinks(t *testing.T) {
+	page := `<html><head>
+		<base href="https://example.com/blog/">
+		<link rel="stylesheet" href="/style.css">
+		<link rel="alternate" type="text/html" href="/other">
+		<link rel="alternate" type="application/rss+xml" title=" Posts " href="rss.xml">
+		<link rel="Alternate feed" type="application/atom+xml; charset=utf-8" href="https://example.com/atom.xml">
+		<link rel="alternate" type="application/rss+xml" href="rss.xml">
+		</head><body>
+		<link rel="alternate" type="application/feed+json" href="/feed.json">
+		</body></html>`
+	pageURL, err := url.Parse("https://example.com/")
+	testutils.AssertNoErr(err, t)
+	got := findFeedLinks(strings.NewReader(page), pageURL)
+	testutils.AssertInts(len(got), 2, t)
+	testutils.AssertStrings(got[0].url, "https://example.com/blog/rss.xml", t)
+	testutils.AssertStrings(got[0].title, "Posts", t)
+	testutils.AssertStrings(got[1].url, "https://example.com/atom.xml", t)
+}
+
+func TestParseBrowseArgs(t *testing.T) {
+	type testStruct struct {
+		name          string
+		args          []string
+		isErrExpected bool
+		expected      browseOptions
+	}
+	tests := []testStruct{
+		{"no args", []string{}, false, browseOptions{defaultBrowseLimit, false, false}},
+		{"limit", []string{"10"}, false, browseOptions{10, false, false}},
+		{"enclosures before limit", []string{"--enclosures", "5"}, false, browseOptions{5, true, false}},
+		{"enclosures after limit", []string{"5", "--enclosures"}, false, browseOptions{5, true, false}},
+		{"full and enclosures", []string{"--full", "3", "--enclosures"}, false, browseOptions{3, true, true}},
+		{"invalid limit", []string{"five"}, true, browseOptions{}},
+		{"too many args", []string{"5", "10"}, true, browseOptions{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			got, err := parseBrowseArgs(test.args, &buf)
+			switch test.isErrExpected {
+			case true:
+				testutils.AssertHasErr(err, t)
+			case false:
+				testutils.AssertNoErr(err, t)
+				if got != test.expected {
+					t.Errorf("got: %+v\nwant: %+v\n", got, test.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatEnclosure(t *testing.T) {
+	type testStruct struct {
+		name      string
+		enclosure database.Enclosure
+		expected  string
+	}
+	tests := []testStruct{
+		{
+			"all details",
+			database.Enclosure{
+				Url:             "https://cdn.example.com/episode-2.mp3",
+				MimeType:        sql.NullString{String: "audio/mpeg", Valid: true},
+				LengthBytes:     sql.NullInt64{Int64: 24986239, Valid: true},
+				DurationSeconds: sql.NullInt32{Int32: 3723, Valid: true},
+			},
+			"https://cdn.example.com/episode-2.mp3 (audio/mpeg, 23.8 MB, 1h2m3s)",
+		},
+		{
+			"type only",
+			database.Enclosure{
+				Url:      "https://cdn.example.com/episode-1.ogg",
+				MimeType: sql.NullString{String: "audio/ogg", Valid: true},
+			},
+			"https://cdn.example.com/episode-1.ogg (audio/ogg)",
+		},
+		{"url only", database.Enclosure{Url: "https://cdn.example.com/video.mp4"}, "https://cdn.example.com/video.mp4"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testutils.AssertStrings(formatEnclosure(test.enclosure), test.expected, t)
+		})
+	}
+}
+
+func TestGetBackoffDuration(t *testing.T) {
+	type testStruct struct {
+		consecutiveFailures int32
+		expected            time.Duration
+	}
+	tests := []testStruct{
+		{1, 5 * time.Minute},
+		{2, 10 * time.Minute},
+		{4, 40 * time.Minute},
+		{20, 24 * time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d failures", test.consecutiveFailures), func(t *testing.T) {
+			got := getBackoffDuration(test.consecutiveFailures)
+			if got != test.expected {
+				t.Errorf("got: %v\nwant: %v\n", got, test.expected)
+			}
+		})
+	}
+}
+
 func processBufAndAssertStrings(t *testing.T, buf bytes.Buffer, expectedStrings []string) {
 	scanner := bufio.NewScanner(&buf)
 	gotStrings := []string{}
@@ -459,7 +1179,7 @@ func doLogin(t *testing.T, commands *commands, state *database.State, username s
 	loginCmd, err := ParseCommand([]string{"test-program", "login", username})
 	testutils.AssertNoErr(err, t)
 	loginBuf := bytes.Buffer{}
-	loginErr := commands.ExecCommand(loginCmd, &loginBuf, state)
+	loginErr := commands.ExecCommand(context.Background(), loginCmd, &loginBuf, state)
 	testutils.AssertNoErr(loginErr, t)
 }
 
@@ -467,7 +1187,7 @@ func runFollowing(t *testing.T, commands *commands, state *database.State) (buf
 	followingCmd, err := ParseCommand([]string{"test-program", "following"})
 	testutils.AssertNoErr(err, t)
 	followingBuf := bytes.Buffer{}
-	followingErr := commands.ExecCommand(followingCmd, &followingBuf, state)
+	followingErr := commands.ExecCommand(context.Background(), followingCmd, &followingBuf, state)
 	testutils.AssertNoErr(followingErr, t)
 	return followingBuf
 
@@ -477,7 +1197,7 @@ func follow(t *testing.T, commands *commands, state *database.State, feedUrl str
 	followCmd, err := ParseCommand([]string{"test-program", "follow", feedUrl})
 	testutils.AssertNoErr(err, t)
 	followBuf := bytes.Buffer{}
-	followErr := commands.ExecCommand(followCmd, &followBuf, state)
+	followErr := commands.ExecCommand(context.Background(), followCmd, &followBuf, state)
 	testutils.AssertNoErr(followErr, t)
 }
 
@@ -485,7 +1205,7 @@ func addFeed(t *testing.T, commands *commands, state *database.State, feedname,
 	addFeedCmd, err := ParseCommand([]string{"test-program", "addfeed", feedname, feedURL})
 	testutils.AssertNoErr(err, t)
 	addFeedBuf := bytes.Buffer{}
-	addFeedErr := commands.ExecCommand(addFeedCmd, &addFeedBuf, state)
+	addFeedErr := commands.ExecCommand(context.Background(), addFeedCmd, &addFeedBuf, state)
 	testutils.AssertNoErr(addFeedErr, t)
 }
 
@@ -493,7 +1213,7 @@ func registerUser(t *testing.T, commands *commands, state *database.State, usern
 	registerCmd, err := ParseCommand([]string{"test-program", "register", username})
 	testutils.AssertNoErr(err, t)
 	registerBuf := bytes.Buffer{}
-	registerErr := commands.ExecCommand(registerCmd, &registerBuf, state)
+	registerErr := commands.ExecCommand(context.Background(), registerCmd, &registerBuf, state)
 	testutils.AssertNoErr(registerErr, t)
 }
 
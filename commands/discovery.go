@@ -0,0 +1,251 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+	"sync"
+
+	definederrors "github.com/sohWenMing/aggregator/defined_errors"
+	"github.com/sohWenMing/aggregator/internal/database"
+	"github.com/sohWenMing/aggregator/rss_parsing"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// paths that are tried on the site when a page doesn't link to its feed, in order of preference
+var commonFeedPaths = []string{"/feed", "/rss", "/feed.xml", "/rss.xml", "/atom.xml", "/index.xml", "/feed.json"}
+
+var feedMediaTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+type feedCandidate struct {
+	url   string
+	title string
+	//set when the candidate was found by probing, which has already fetched and parsed it
+	result *fetchResult
+}
+
+// works out the feed to subscribe to for the url given to addfeed. if the url is a feed it's used as it is,
+// otherwise it's treated as a web page and the feeds it links to are looked for. a single feed is picked
+// automatically, if there is more than one they are listed, and pick chooses between them. the chosen feed is
+// returned already fetched and parsed
+func resolveFeedURL(ctx context.Context, w io.Writer, inputURL string, pick int, state *database.State) (feedURL string, result fetchResult, err error) {
+	result, fetchErr := fetchFeed(ctx, inputURL, cacheHeaders{}, state, nil)
+	if fetchErr == nil {
+		if pick > 0 {
+			fmt.Fprintf(w, "%s is a feed, --pick is only for choosing between the feeds found on a web page\n", inputURL)
+			return "", fetchResult{}, fmt.Errorf("--pick %d given for feed %s %w", pick, inputURL, definederrors.ErrorInput)
+		}
+		return inputURL, result, nil
+	}
+	if !isNotAFeedErr(fetchErr) {
+		return "", fetchResult{}, fetchErr
+	}
+
+	candidates, err := discoverFeeds(ctx, inputURL, state)
+	if err != nil {
+		return "", fetchResult{}, err
+	}
+	var chosen feedCandidate
+	switch {
+	case len(candidates) == 0:
+		fmt.Fprintf(w, "%s is not a feed, and no feeds could be found on it\n", inputURL)
+		return "", fetchResult{}, fmt.Errorf("%s %w", inputURL, definederrors.ErrorNoFeedsFound)
+	case pick > len(candidates):
+		printFeedCandidates(w, candidates)
+		return "", fetchResult{}, fmt.Errorf("--pick %d is out of range, %d feeds were found %w", pick, len(candidates), definederrors.ErrorInput)
+	case pick > 0:
+		chosen = candidates[pick-1]
+	case len(candidates) == 1:
+		chosen = candidates[0]
+	default:
+		fmt.Fprintf(w, "found %d feeds on %s:\n", len(candidates), inputURL)
+		printFeedCandidates(w, candidates)
+		fmt.Fprintln(w, "run addfeed again with --pick <number> to choose one")
+		return "", fetchResult{}, fmt.Errorf("%s %w", inputURL, definederrors.ErrorMultipleFeedsFound)
+	}
+	fmt.Fprintf(w, "found feed %s on %s\n", chosen.url, inputURL)
+	if chosen.result != nil {
+		return chosen.url, *chosen.result, nil
+	}
+
+	//feeds found from link tags haven't been fetched yet
+	result, fetchErr = fetchFeed(ctx, chosen.url, cacheHeaders{}, state, nil)
+	if fetchErr != nil {
+		return "", fetchResult{}, fetchErr
+	}
+	return chosen.url, result, nil
+}
+
+func printFeedCandidates(w io.Writer, candidates []feedCandidate) {
+	for i, candidate := range candidates {
+		if candidate.title == "" {
+			fmt.Fprintf(w, "  %d. %s\n", i+1, candidate.url)
+			continue
+		}
+		fmt.Fprintf(w, "  %d. %s (%s)\n", i+1, candidate.url, candidate.title)
+	}
+}
+
+// errors from parsing the response rather than from fetching it, which means the url is probably a web page
+func isNotAFeedErr(err error) bool {
+	var xmlSyntaxErr *xml.SyntaxError
+	var jsonSyntaxErr *json.SyntaxError
+	return errors.Is(err, rss_parsing.ErrorUnknownFeedFormat) || errors.As(err, &xmlSyntaxErr) || errors.As(err, &jsonSyntaxErr)
+}
+
+// looks for the feeds of the web page at pageURL. the page's <link rel="alternate"> tags are used if it has
+// any, otherwise the common feed paths are tried on the page's site
+func discoverFeeds(ctx context.Context, pageURL string, state *database.State) (candidates []feedCandidate, err error) {
+	page, finalURL, err := fetchPage(ctx, pageURL, state)
+	if err != nil {
+		return nil, err
+	}
+	candidates = findFeedLinks(page, finalURL)
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+	return probeCommonFeedPaths(ctx, finalURL, state), nil
+}
+
+// fetches the web page at pageURL, returning it along with the url it was fetched from after any redirects
+func fetchPage(ctx context.Context, pageURL string, state *database.State) (page io.Reader, finalURL *url.URL, err error) {
+	req, deadline, err := getReqAndDeadline(ctx, pageURL, cacheHeaders{}, state.Cfg.GetFetchTimeout())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer deadline.stop()
+	res, err := state.Client.Do(req)
+	if err != nil {
+		return nil, nil, deadline.wrapErr(req.Context(), err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, nil, &statusCodeError{res.StatusCode, res.Status}
+	}
+	maxBodyBytes := state.Cfg.GetMaxBodyBytes()
+	body, err := io.ReadAll(&maxBytesReader{
+		reader:    io.LimitReader(res.Body, maxBodyBytes+1),
+		remaining: maxBodyBytes,
+	})
+	if err != nil {
+		return nil, nil, deadline.wrapErr(req.Context(), err)
+	}
+	return bytes.NewReader(body), res.Request.URL, nil
+}
+
+// finds the <link rel="alternate"> tags in the page that point at feeds, resolving their urls against the
+// page's url, or its <base> if it has one
+func findFeedLinks(page io.Reader, pageURL *url.URL) (candidates []feedCandidate) {
+	baseURL := pageURL
+	seenURLs := map[string]bool{}
+	tokenizer := html.NewTokenizer(page)
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return candidates
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		switch token.DataAtom {
+		case atom.Base:
+			if href, err := url.Parse(getTokenAttr(token, "href")); err == nil {
+				baseURL = pageURL.ResolveReference(href)
+			}
+		case atom.Link:
+			if !hasRel(getTokenAttr(token, "rel"), "alternate") || !isFeedMediaType(getTokenAttr(token, "type")) {
+				continue
+			}
+			href, err := url.Parse(strings.TrimSpace(getTokenAttr(token, "href")))
+			if err != nil || href.String() == "" {
+				continue
+			}
+			feedURL := baseURL.ResolveReference(href).String()
+			if seenURLs[feedURL] {
+				continue
+			}
+			seenURLs[feedURL] = true
+			candidates = append(candidates, feedCandidate{
+				url:   feedURL,
+				title: strings.TrimSpace(getTokenAttr(token, "title")),
+			})
+		case atom.Body:
+			//feed links belong in the head, so the rest of the page doesn't need to be read
+			return candidates
+		}
+	}
+}
+
+// tries each of the common feed paths on the site of siteURL at the same time, returning the ones that are
+// feeds in the order of commonFeedPaths. each candidate keeps its parsed feed, so the one that is chosen
+// doesn't have to be fetched again
+func probeCommonFeedPaths(ctx context.Context, siteURL *url.URL, state *database.State) (candidates []feedCandidate) {
+	results := make([]*feedCandidate, len(commonFeedPaths))
+	var wg sync.WaitGroup
+	for i, path := range commonFeedPaths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeURL := siteURL.ResolveReference(&url.URL{Path: path}).String()
+			result, err := fetchFeed(ctx, probeURL, cacheHeaders{}, state, nil)
+			if err != nil {
+				return
+			}
+			results[i] = &feedCandidate{url: probeURL, title: result.feed.Channel.Title, result: &result}
+		}()
+	}
+	wg.Wait()
+
+	//paths like /feed and /rss are often redirects to the same feed, which would otherwise be listed twice
+	seenTitles := map[string]bool{}
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if result.title != "" && seenTitles[result.title] {
+			continue
+		}
+		seenTitles[result.title] = true
+		candidates = append(candidates, *result)
+	}
+	return candidates
+}
+
+func hasRel(rel string, want string) bool {
+	for _, value := range strings.Fields(rel) {
+		if strings.EqualFold(value, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func isFeedMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return feedMediaTypes[mediaType]
+}
+
+func getTokenAttr(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
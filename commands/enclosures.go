@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	definederrors "github.com/sohWenMing/aggregator/defined_errors"
+	"github.com/sohWenMing/aggregator/internal/database"
+	"github.com/sohWenMing/aggregator/rss_parsing"
+)
+
+func writeEnclosuresToDB(ctx context.Context, postID uuid.UUID, w io.Writer, enclosures []rss_parsing.Enclosure, state *database.State) {
+	for _, enclosure := range enclosures {
+		params := database.UpsertEnclosureParams{
+			ID:              uuid.New(),
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+			PostID:          postID,
+			Url:             enclosure.URL,
+			MimeType:        genNullableString(enclosure.MimeType),
+			LengthBytes:     genNullableInt64(enclosure.Length),
+			DurationSeconds: genNullableInt32(int(enclosure.Duration / time.Second)),
+		}
+		_, err := state.Db.UpsertEnclosure(ctx, params)
+		if err != nil {
+			fmt.Fprintf(w, "enclosure %s could not be saved: %v\n", enclosure.URL, err)
+		}
+	}
+}
+
+// lists the enclosures of a post, in the form of "enclosures <post id|post url>". the id of a post is shown
+// by browse --enclosures
+func handlerGetEnclosures(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+	if len(cmd.args) != 1 {
+		fmt.Fprintln(w, definederrors.ErrorWrongNumArgs.Error())
+		return definederrors.ErrorWrongNumArgs
+	}
+
+	var enclosures []database.Enclosure
+	postID, parseErr := uuid.Parse(cmd.args[0])
+	switch parseErr == nil {
+	case true:
+		enclosures, err = state.Db.GetEnclosuresForPost(ctx, postID)
+	case false:
+		var rows []database.GetEnclosuresForPostURLRow
+		rows, err = state.Db.GetEnclosuresForPostURL(ctx, cmd.args[0])
+		for _, row := range rows {
+			enclosures = append(enclosures, database.Enclosure{
+				Url:             row.Url,
+				MimeType:        row.MimeType,
+				LengthBytes:     row.LengthBytes,
+				DurationSeconds: row.DurationSeconds,
+			})
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(w, "enclosures for post %s could not be retrieved\n", cmd.args[0])
+		return err
+	}
+	if len(enclosures) == 0 {
+		fmt.Fprintf(w, "post %s has no enclosures\n", cmd.args[0])
+		return nil
+	}
+	for _, enclosure := range enclosures {
+		fmt.Fprintln(w, formatEnclosure(enclosure))
+	}
+	return nil
+}
+
+func printEnclosuresForPost(ctx context.Context, postID uuid.UUID, w io.Writer, state *database.State) {
+	enclosures, err := state.Db.GetEnclosuresForPost(ctx, postID)
+	if err != nil {
+		fmt.Fprintf(w, "Enclosures could not be retrieved: %v\n", err)
+		return
+	}
+	if len(enclosures) == 0 {
+		fmt.Fprintln(w, "Enclosures: none")
+		return
+	}
+	fmt.Fprintln(w, "Enclosures:")
+	for _, enclosure := range enclosures {
+		fmt.Fprintf(w, "  %s\n", formatEnclosure(enclosure))
+	}
+}
+
+// formats an enclosure as its url followed by whichever of its type, size and duration are known,
+// e.g. "https://example.com/episode.mp3 (audio/mpeg, 23.8 MB, 1h2m3s)"
+func formatEnclosure(enclosure database.Enclosure) string {
+	details := []string{}
+	if enclosure.MimeType.Valid {
+		details = append(details, enclosure.MimeType.String)
+	}
+	if enclosure.LengthBytes.Valid {
+		details = append(details, formatBytes(enclosure.LengthBytes.Int64))
+	}
+	if enclosure.DurationSeconds.Valid {
+		details = append(details, (time.Duration(enclosure.DurationSeconds.Int32) * time.Second).String())
+	}
+	if len(details) == 0 {
+		return enclosure.Url
+	}
+	return fmt.Sprintf("%s (%s)", enclosure.Url, strings.Join(details, ", "))
+}
+
+func formatBytes(numBytes int64) string {
+	const unit = 1024
+	if numBytes < unit {
+		return fmt.Sprintf("%d B", numBytes)
+	}
+	value := float64(numBytes) / unit
+	units := []string{"KB", "MB", "GB", "TB"}
+	i := 0
+	for value >= unit && i < len(units)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
+
+// returns an invalid NullInt64 for zero, which the feed parsers use for a length that wasn't given
+func genNullableInt64(input int64) (sqlNullableInt sql.NullInt64) {
+	if input == 0 {
+		sqlNullableInt.Valid = false
+		return sqlNullableInt
+	}
+	sqlNullableInt.Int64 = input
+	sqlNullableInt.Valid = true
+	return sqlNullableInt
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	definederrors "github.com/sohWenMing/aggregator/defined_errors"
+	"github.com/sohWenMing/aggregator/internal/database"
+)
+
+// number of 404 responses in a row after which a feed is treated as dead
+const maxConsecutiveNotFound = 5
+
+const maxRedirects = 10
+
+// wraps the CheckRedirect of the client so that permanent redirects are recorded as they are followed. the
+// returned func gives the url that the feed has permanently moved to, which is the target of the last
+// redirect in an unbroken chain of 301/308 responses from the original url, or an empty string if the first
+// redirect was temporary
+func trackPermanentRedirects(client *http.Client) (getPermanentRedirectURL func() string) {
+	permanentRedirectURL := ""
+	isChainPermanent := true
+	existingCheckRedirect := client.CheckRedirect
+
+	client.CheckRedirect = func(redirectReq *http.Request, via []*http.Request) error {
+		if existingCheckRedirect != nil {
+			checkErr := existingCheckRedirect(redirectReq, via)
+			if checkErr != nil {
+				return checkErr
+			}
+		} else if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		statusCode := redirectReq.Response.StatusCode
+		isPermanent := statusCode == http.StatusMovedPermanently || statusCode == http.StatusPermanentRedirect
+		if isChainPermanent && isPermanent {
+			permanentRedirectURL = redirectReq.URL.String()
+			return nil
+		}
+		isChainPermanent = false
+		return nil
+	}
+	return func() string {
+		return permanentRedirectURL
+	}
+}
+
+func updateURLAfterRedirect(ctx context.Context, feed database.Feed, w io.Writer, newURL string, state *database.State) {
+	params := database.UpdateFeedURLParams{
+		Url:       newURL,
+		UpdatedAt: time.Now(),
+		ID:        feed.ID,
+	}
+	updateErr := state.Db.UpdateFeedURL(ctx, params)
+	if updateErr != nil {
+		_, isUniqueViolation, _, _ := database.CheckPqErr(updateErr)
+		if isUniqueViolation {
+			fmt.Fprintf(w, "feed %s has moved to %s, but another feed already has that url\n", feed.Url, newURL)
+			return
+		}
+		fmt.Fprintln(w, updateErr.Error())
+		return
+	}
+	fmt.Fprintf(w, "feed %s has permanently moved, url updated to %s\n", feed.Url, newURL)
+}
+
+// disables the feed if the server says it is gone for good, or if it has not been found too many times in a row
+func disableIfGone(ctx context.Context, feed database.Feed, w io.Writer, statusCode int, state *database.State) {
+	var reason string
+	switch {
+	case statusCode == http.StatusGone:
+		reason = "feed returned 410 Gone"
+	case statusCode == http.StatusNotFound && feed.ConsecutiveNotFound+1 >= maxConsecutiveNotFound:
+		reason = fmt.Sprintf("feed returned 404 Not Found %d times in a row", feed.ConsecutiveNotFound+1)
+	default:
+		return
+	}
+	disableErr := setFeedDisabled(ctx, feed.ID, reason, state)
+	if disableErr != nil {
+		fmt.Fprintln(w, disableErr.Error())
+		return
+	}
+	fmt.Fprintf(w, "feed %s has been disabled: %s\n", feed.Url, reason)
+}
+
+func setFeedDisabled(ctx context.Context, feedID uuid.UUID, reason string, state *database.State) (err error) {
+	params := database.DisableFeedParams{
+		DisabledAt:     sql.NullTime{Time: time.Now(), Valid: true},
+		DisabledReason: genNullableString(reason),
+		UpdatedAt:      time.Now(),
+		ID:             feedID,
+	}
+	return state.Db.DisableFeed(ctx, params)
+}
+
+// handles "feed enable <url>" and "feed disable <url>"
+func handlerFeed(ctx context.Context, cmd enteredCommand, w io.Writer, state *database.State) (err error) {
+	if len(cmd.args) != 2 {
+		fmt.Fprintln(w, "usage: feed <enable|disable> <url>")
+		return fmt.Errorf("args passed into handlerFeed %v %w", cmd.args, definederrors.ErrorWrongNumArgs)
+	}
+	action := cmd.args[0]
+	feedURL := cmd.args[1]
+
+	feedID, err := state.Db.GetFeedIdByURL(ctx, feedURL)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			fmt.Fprintf(w, "feed with url %s could not be found\n", feedURL)
+		}
+		return err
+	}
+
+	switch action {
+	case "enable":
+		params := database.EnableFeedParams{
+			UpdatedAt: time.Now(),
+			ID:        feedID,
+		}
+		enableErr := state.Db.EnableFeed(ctx, params)
+		if enableErr != nil {
+			fmt.Fprintln(w, "error occured when attempting to enable feed")
+			return enableErr
+		}
+		fmt.Fprintf(w, "feed %s has been enabled\n", feedURL)
+	case "disable":
+		disableErr := setFeedDisabled(ctx, feedID, "disabled by hand", state)
+		if disableErr != nil {
+			fmt.Fprintln(w, "error occured when attempting to disable feed")
+			return disableErr
+		}
+		fmt.Fprintf(w, "feed %s has been disabled\n", feedURL)
+	default:
+		fmt.Fprintln(w, "usage: feed <enable|disable> <url>")
+		return fmt.Errorf("unknown feed action %s %w", action, definederrors.ErrorInput)
+	}
+	return nil
+}
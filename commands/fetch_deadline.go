@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// fetchDeadline cancels a request once it has spent its timeout on the network. the deadline is paused while
+// the items of the response are written to the database, so a slow database can't make a fast fetch time out.
+// pause and resume are only called from the goroutine that reads the response
+type fetchDeadline struct {
+	cancel    context.CancelCauseFunc
+	timer     *time.Timer
+	remaining time.Duration
+	resumedAt time.Time
+	expired   bool
+}
+
+func newFetchDeadline(ctx context.Context, timeout time.Duration) (reqCtx context.Context, deadline *fetchDeadline) {
+	reqCtx, cancel := context.WithCancelCause(ctx)
+	deadline = &fetchDeadline{
+		cancel:    cancel,
+		remaining: timeout,
+		resumedAt: time.Now(),
+	}
+	deadline.timer = time.AfterFunc(timeout, func() {
+		cancel(context.DeadlineExceeded)
+	})
+	return reqCtx, deadline
+}
+
+func (d *fetchDeadline) pause() {
+	if !d.timer.Stop() {
+		d.expired = true
+		return
+	}
+	d.remaining -= time.Since(d.resumedAt)
+}
+
+func (d *fetchDeadline) resume() {
+	if d.expired {
+		return
+	}
+	d.resumedAt = time.Now()
+	d.timer.Reset(max(d.remaining, 0))
+}
+
+// releases the request's context, to be called once the response is done with
+func (d *fetchDeadline) stop() {
+	d.timer.Stop()
+	d.cancel(nil)
+}
+
+// the http client reports a cancelled request as context.Canceled, so when the cancellation was the deadline
+// running out, the error is wrapped to match context.DeadlineExceeded as well
+func (d *fetchDeadline) wrapErr(reqCtx context.Context, err error) error {
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if context.Cause(reqCtx) != context.DeadlineExceeded {
+		return err
+	}
+	return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sohWenMing/aggregator/internal/database"
+)
+
+const (
+	baseFetchBackoff = 5 * time.Minute
+	maxFetchBackoff  = 24 * time.Hour
+)
+
+// the backoff doubles with every consecutive failure, starting at baseFetchBackoff and capped at maxFetchBackoff
+func getBackoffDuration(consecutiveFailures int32) time.Duration {
+	backoff := baseFetchBackoff
+	for i := int32(1); i < consecutiveFailures; i++ {
+		backoff *= 2
+		if backoff >= maxFetchBackoff {
+			return maxFetchBackoff
+		}
+	}
+	return backoff
+}
+
+func recordFetchSuccess(ctx context.Context, feed database.Feed, w io.Writer, statusCode int, state *database.State) {
+	params := database.RecordFeedFetchSuccessParams{
+		LastHttpStatus: genNullableInt32(statusCode),
+		UpdatedAt:      time.Now(),
+		ID:             feed.ID,
+	}
+	recordErr := state.Db.RecordFeedFetchSuccess(ctx, params)
+	if recordErr != nil {
+		fmt.Fprintln(w, recordErr.Error())
+	}
+}
+
+// records the error against the feed, and pushes back the next time it can be fetched based on how many
+// times in a row it has now failed
+func recordFetchFailure(ctx context.Context, feed database.Feed, w io.Writer, statusCode int, fetchErr error, state *database.State) {
+	backoff := getBackoffDuration(feed.ConsecutiveFailures + 1)
+	params := database.RecordFeedFetchFailureParams{
+		LastError:      genNullableString(fetchErr.Error()),
+		LastHttpStatus: genNullableInt32(statusCode),
+		NextEligibleAt: sql.NullTime{Time: time.Now().Add(backoff), Valid: true},
+		UpdatedAt:      time.Now(),
+		ID:             feed.ID,
+	}
+	recordErr := state.Db.RecordFeedFetchFailure(ctx, params)
+	if recordErr != nil {
+		fmt.Fprintln(w, recordErr.Error())
+	}
+}
+
+func handlerGetFeedErrors(ctx context.Context, w io.Writer, state *database.State) (err error) {
+	feeds, err := state.Db.GetFeedsWithErrors(ctx)
+	if err != nil {
+		fmt.Fprintln(w, "error occured while getting feeds with errors")
+		return err
+	}
+	if len(feeds) == 0 {
+		fmt.Fprintln(w, "no feeds are currently failing")
+		return nil
+	}
+	for _, feed := range feeds {
+		fmt.Fprintln(w, "=======================")
+		fmt.Fprintf(w, "FeedName: %s\n", feed.FeedName)
+		fmt.Fprintf(w, "Url: %s\n", feed.FeedUrl)
+		fmt.Fprintf(w, "ConsecutiveFailures: %d\n", feed.ConsecutiveFailures)
+		if feed.LastHttpStatus.Valid {
+			fmt.Fprintf(w, "LastHttpStatus: %d\n", feed.LastHttpStatus.Int32)
+		}
+		fmt.Fprintf(w, "LastError: %s\n", feed.LastError.String)
+		if feed.DisabledAt.Valid {
+			fmt.Fprintf(w, "DisabledAt: %s (%s)\n", feed.DisabledAt.Time.UTC(), feed.DisabledReason.String)
+		}
+		if feed.NextEligibleAt.Valid {
+			fmt.Fprintf(w, "NextFetchAfter: %s\n", feed.NextEligibleAt.Time.UTC())
+		}
+		fmt.Fprintln(w, "=======================")
+	}
+	return nil
+}
+
+func genNullableInt32(input int) (sqlNullableInt sql.NullInt32) {
+	if input == 0 {
+		sqlNullableInt.Valid = false
+		return sqlNullableInt
+	}
+	sqlNullableInt.Int32 = int32(input)
+	sqlNullableInt.Valid = true
+	return sqlNullableInt
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sohWenMing/aggregator/internal/database"
+	"github.com/sohWenMing/aggregator/rss_parsing"
+)
+
+// stores the refresh hints that were declared in the feed, along with the next time the feed is allowed
+// to be fetched based on them
+func saveRefreshHints(ctx context.Context, feed database.Feed, w io.Writer, hints rss_parsing.RefreshHints, state *database.State) {
+	skipHours := []string{}
+	for _, skipHour := range hints.SkipHours {
+		skipHours = append(skipHours, strconv.Itoa(skipHour))
+	}
+	skipDays := []string{}
+	for _, skipDay := range hints.SkipDays {
+		skipDays = append(skipDays, skipDay.String())
+	}
+
+	params := database.UpdateFeedRefreshHintsParams{
+		TtlMinutes:         genNullableInt32(int(hints.TTL / time.Minute)),
+		SkipHours:          genNullableString(strings.Join(skipHours, ",")),
+		SkipDays:           genNullableString(strings.Join(skipDays, ",")),
+		SyUpdatePeriod:     genNullableString(hints.UpdatePeriod),
+		SyUpdateFrequency:  genNullableInt32(hints.UpdateFrequency),
+		NextAllowedFetchAt: getNextAllowedFetch(hints, time.Now()),
+		UpdatedAt:          time.Now(),
+		ID:                 feed.ID,
+	}
+	updateErr := state.Db.UpdateFeedRefreshHints(ctx, params)
+	if updateErr != nil {
+		fmt.Fprintln(w, updateErr.Error())
+	}
+}
+
+// used when the feed wasn't modified, so the hints saved from the last full fetch are used to schedule the next one
+func scheduleFromStoredHints(ctx context.Context, feed database.Feed, w io.Writer, state *database.State) {
+	params := database.SetFeedNextAllowedFetchParams{
+		NextAllowedFetchAt: getNextAllowedFetch(getStoredRefreshHints(feed), time.Now()),
+		UpdatedAt:          time.Now(),
+		ID:                 feed.ID,
+	}
+	updateErr := state.Db.SetFeedNextAllowedFetch(ctx, params)
+	if updateErr != nil {
+		fmt.Fprintln(w, updateErr.Error())
+	}
+}
+
+func getStoredRefreshHints(feed database.Feed) rss_parsing.RefreshHints {
+	return rss_parsing.ParseRefreshHints(
+		strconv.Itoa(int(feed.TtlMinutes.Int32)),
+		strings.Split(feed.SkipHours.String, ","),
+		strings.Split(feed.SkipDays.String, ","),
+		feed.SyUpdatePeriod.String,
+		strconv.Itoa(int(feed.SyUpdateFrequency.Int32)),
+	)
+}
+
+func getNextAllowedFetch(hints rss_parsing.RefreshHints, fetchedAt time.Time) (nextAllowedFetch sql.NullTime) {
+	if !hints.HasHints() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: hints.NextAllowedFetch(fetchedAt), Valid: true}
+}
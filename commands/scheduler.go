@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sohWenMing/aggregator/internal/database"
+)
+
+const (
+	defaultMinPollInterval = 15 * time.Minute
+	defaultMaxPollInterval = 24 * time.Hour
+)
+
+// how long a claimed feed is kept out of the queue while it is being scraped. the lease is replaced once the
+// scrape is done, by scheduleNextFetch on success, by the backoff on failure and by releaseFeedClaim when the
+// scrape is cancelled, so it only runs out if the aggregator dies part way through a scrape
+const feedClaimLease = 30 * time.Minute
+
+// the range that the adaptive poll interval of every feed is kept within
+type pollBounds struct {
+	minInterval time.Duration
+	maxInterval time.Duration
+}
+
+// feeds are polled at half their average posting interval, so that a new post is usually picked up well before
+// the next one is expected. feeds without enough dated posts to work out an average are polled at the minimum
+func getAdaptivePollInterval(averagePostInterval time.Duration, bounds pollBounds) time.Duration {
+	if averagePostInterval <= 0 {
+		return bounds.minInterval
+	}
+	pollInterval := averagePostInterval / 2
+	if pollInterval < bounds.minInterval {
+		return bounds.minInterval
+	}
+	if pollInterval > bounds.maxInterval {
+		return bounds.maxInterval
+	}
+	return pollInterval
+}
+
+// works out the poll interval of the feed from the publication dates of its posts, and stores the next time it is due
+func scheduleNextFetch(ctx context.Context, feed database.Feed, w io.Writer, bounds pollBounds, state *database.State) {
+	averageSeconds, err := state.Db.GetAveragePostInterval(ctx, feed.ID)
+	if err != nil {
+		fmt.Fprintln(w, err.Error())
+		return
+	}
+	averagePostInterval := time.Duration(averageSeconds * float64(time.Second))
+	pollInterval := getAdaptivePollInterval(averagePostInterval, bounds)
+
+	params := database.SetFeedNextFetchParams{
+		NextFetchAt: sql.NullTime{Time: time.Now().Add(pollInterval), Valid: true},
+		UpdatedAt:   time.Now(),
+		ID:          feed.ID,
+	}
+	setErr := state.Db.SetFeedNextFetch(ctx, params)
+	if setErr != nil {
+		fmt.Fprintln(w, setErr.Error())
+		return
+	}
+	fmt.Fprintf(w, "Feed %s will next be polled in %s\n", feed.Name, pollInterval)
+}
+
+// puts a feed whose scrape was cancelled straight back in the queue, rather than leaving it out until the lease runs out
+func releaseFeedClaim(ctx context.Context, feed database.Feed, w io.Writer, state *database.State) {
+	params := database.SetFeedNextFetchParams{
+		NextFetchAt: sql.NullTime{},
+		UpdatedAt:   time.Now(),
+		ID:          feed.ID,
+	}
+	setErr := state.Db.SetFeedNextFetch(ctx, params)
+	if setErr != nil {
+		fmt.Fprintln(w, setErr.Error())
+	}
+}
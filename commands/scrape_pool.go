@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	definederrors "github.com/sohWenMing/aggregator/defined_errors"
+	"github.com/sohWenMing/aggregator/internal/database"
+)
+
+type aggOptions struct {
+	timeBetweenReqs time.Duration
+	numWorkers      int
+	batchSize       int32
+	bounds          pollBounds
+	once            bool
+}
+
+// counts of what happened during a run of agg, printed when it stops
+type aggSummary struct {
+	feedsScraped   atomic.Int64
+	feedsFailed    atomic.Int64
+	postsProcessed atomic.Int64
+}
+
+func (a *aggSummary) print(w io.Writer) {
+	fmt.Fprintf(w, "agg stopped: %d feeds scraped, %d feeds failed, %d posts processed\n",
+		a.feedsScraped.Load(), a.feedsFailed.Load(), a.postsProcessed.Load())
+}
+
+// parses the arguments to agg, in the form of
+// "agg <time_between_reqs> [--workers n] [--batch n] [--min-interval d] [--max-interval d]" or "agg --once [...]".
+// flags can be given before or after the duration. if batch isn't set, one feed is claimed per worker
+func parseAggArgs(args []string, w io.Writer) (options aggOptions, err error) {
+	flagSet := flag.NewFlagSet("agg", flag.ContinueOnError)
+	flagSet.SetOutput(w)
+	numWorkers := flagSet.Int("workers", 1, "number of feeds to scrape concurrently")
+	batchSize := flagSet.Int("batch", 0, "number of feeds to claim on each tick, defaults to the number of workers")
+	minInterval := flagSet.Duration("min-interval", defaultMinPollInterval, "shortest time between polls of a single feed")
+	once := flagSet.Bool("once", false, "scrape every feed that is due one time and then exit")
+	maxInterval := flagSet.Duration("max-interval", defaultMaxPollInterval, "longest time between polls of a single feed")
+
+	positionalArgs, err := parseInterspersedFlags(flagSet, args)
+	if err != nil {
+		return aggOptions{}, err
+	}
+
+	var timeBetweenReqs time.Duration
+	switch {
+	case *once && len(positionalArgs) == 0:
+	case len(positionalArgs) == 1:
+		timeBetweenReqs, err = time.ParseDuration(positionalArgs[0])
+		if err != nil {
+			return aggOptions{}, err
+		}
+	default:
+		return aggOptions{}, fmt.Errorf("args passed into handlerAgg %v %w", args, definederrors.ErrorWrongNumArgs)
+	}
+	if *numWorkers < 1 || *batchSize < 0 {
+		return aggOptions{}, fmt.Errorf("workers must be at least 1 and batch cannot be negative %w", definederrors.ErrorInput)
+	}
+	if *minInterval <= 0 || *maxInterval < *minInterval {
+		return aggOptions{}, fmt.Errorf("min-interval must be positive and no larger than max-interval %w", definederrors.ErrorInput)
+	}
+	if *batchSize == 0 {
+		*batchSize = *numWorkers
+	}
+
+	return aggOptions{
+		timeBetweenReqs: timeBetweenReqs,
+		numWorkers:      *numWorkers,
+		batchSize:       int32(*batchSize),
+		bounds: pollBounds{
+			minInterval: *minInterval,
+			maxInterval: *maxInterval,
+		},
+		once: *once,
+	}, nil
+}
+
+// parses args with flagSet, allowing flags to come before, after or in between the positional args, which
+// are returned in the order they were given
+func parseInterspersedFlags(flagSet *flag.FlagSet, args []string) (positionalArgs []string, err error) {
+	positionalArgs = []string{}
+	remainingArgs := args
+	for len(remainingArgs) > 0 {
+		if parseErr := flagSet.Parse(remainingArgs); parseErr != nil {
+			return nil, parseErr
+		}
+		if flagSet.NArg() == 0 {
+			break
+		}
+		positionalArgs = append(positionalArgs, flagSet.Arg(0))
+		remainingArgs = flagSet.Args()[1:]
+	}
+	return positionalArgs, nil
+}
+
+// lockedWriter serialises writes to the shared writer, so that output from different workers can't interleave
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (n int, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// scrapes the feeds using a pool of numWorkers goroutines. each worker buffers the output for a feed and
+// writes it out in one go once the feed is done, so the output for every feed stays together. once ctx is
+// cancelled no more feeds are handed out, but the workers finish the feeds that they have already started.
+// those are scraped on a context that isn't cancelled along with ctx, the fetch deadline still bounds them
+func scrapeFeedsConcurrently(ctx context.Context, feeds []database.Feed, w io.Writer, state *database.State, options aggOptions, summary *aggSummary) {
+	sharedWriter := &lockedWriter{w: w}
+	feedsChan := make(chan database.Feed)
+	scrapeCtx := context.WithoutCancel(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < options.numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for feed := range feedsChan {
+				feedBuf := bytes.Buffer{}
+				numPosts, scrapeErr := scrapeFeed(scrapeCtx, feed, &feedBuf, state, options.bounds)
+				//posts are written as they're decoded, so a feed that fails part way through can still have written some
+				summary.postsProcessed.Add(int64(numPosts))
+				if scrapeErr != nil {
+					summary.feedsFailed.Add(1)
+					fmt.Fprintln(&feedBuf, scrapeErr.Error())
+				} else {
+					summary.feedsScraped.Add(1)
+				}
+				sharedWriter.Write(feedBuf.Bytes())
+			}
+		}()
+	}
+
+dispatchLoop:
+	for _, feed := range feeds {
+		select {
+		case feedsChan <- feed:
+		case <-ctx.Done():
+			break dispatchLoop
+		}
+	}
+	close(feedsChan)
+	wg.Wait()
+}
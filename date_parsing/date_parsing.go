@@ -0,0 +1,100 @@
+package date_parsing
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrorUnparseableDate = errors.New("date did not match any known layout")
+
+// name is used when reporting which layout matched, so that the layouts that are actually being hit can be counted
+type dateLayout struct {
+	name   string
+	layout string
+}
+
+// ordered from most to least common in real world feeds. "2" is used for the day rather than "02" so that
+// single digit days are accepted by the same layouts
+var dateLayouts = []dateLayout{
+	{"RFC1123Z", "Mon, 2 Jan 2006 15:04:05 -0700"},
+	{"RFC1123", "Mon, 2 Jan 2006 15:04:05 MST"},
+	{"RFC3339", time.RFC3339Nano},
+	{"RFC1123Z no seconds", "Mon, 2 Jan 2006 15:04 -0700"},
+	{"RFC1123 no seconds", "Mon, 2 Jan 2006 15:04 MST"},
+	{"RFC1123Z no weekday", "2 Jan 2006 15:04:05 -0700"},
+	{"RFC1123 no weekday", "2 Jan 2006 15:04:05 MST"},
+	{"RFC1123Z full month", "Mon, 2 January 2006 15:04:05 -0700"},
+	{"RFC1123 full month", "Mon, 2 January 2006 15:04:05 MST"},
+	{"RFC1123Z two digit year", "Mon, 2 Jan 06 15:04:05 -0700"},
+	{"RFC1123 two digit year", "Mon, 2 Jan 06 15:04:05 MST"},
+	{"RFC850", time.RFC850},
+	{"ANSIC", time.ANSIC},
+	{"UnixDate", time.UnixDate},
+	{"ISO8601 no seconds", "2006-01-02T15:04Z07:00"},
+	{"ISO8601 basic offset", "2006-01-02T15:04:05-0700"},
+	{"ISO8601 no zone", "2006-01-02T15:04:05"},
+	{"ISO8601 no seconds no zone", "2006-01-02T15:04"},
+	{"ISO8601 space separated", "2006-01-02 15:04:05 -0700"},
+	{"ISO8601 space separated no zone", "2006-01-02 15:04:05"},
+	{"W3CDTF date", "2006-01-02"},
+	{"W3CDTF month", "2006-01"},
+}
+
+// go gives zone abbreviations it doesn't know about an offset of zero, so the abbreviations from RFC 822
+// and a handful of other common ones are mapped to their real offsets
+var zoneOffsets = map[string]int{
+	"EST":  -5 * 60 * 60,
+	"EDT":  -4 * 60 * 60,
+	"CST":  -6 * 60 * 60,
+	"CDT":  -5 * 60 * 60,
+	"MST":  -7 * 60 * 60,
+	"MDT":  -6 * 60 * 60,
+	"PST":  -8 * 60 * 60,
+	"PDT":  -7 * 60 * 60,
+	"AKST": -9 * 60 * 60,
+	"AKDT": -8 * 60 * 60,
+	"HST":  -10 * 60 * 60,
+	"BST":  1 * 60 * 60,
+	"CET":  1 * 60 * 60,
+	"CEST": 2 * 60 * 60,
+	"EET":  2 * 60 * 60,
+	"EEST": 3 * 60 * 60,
+	"SGT":  8 * 60 * 60,
+	"JST":  9 * 60 * 60,
+	"KST":  9 * 60 * 60,
+	"AEST": 10 * 60 * 60,
+	"AEDT": 11 * 60 * 60,
+}
+
+// ParseDate tries each of the known feed date layouts in turn. the parsed date is always returned in UTC,
+// along with the name of the layout that matched it
+func ParseDate(input string) (parsedDate time.Time, layoutName string, err error) {
+	trimmedInput := strings.Join(strings.Fields(input), " ")
+	if trimmedInput == "" {
+		return time.Time{}, "", ErrorUnparseableDate
+	}
+
+	for _, dateLayout := range dateLayouts {
+		parsed, parseErr := time.Parse(dateLayout.layout, trimmedInput)
+		if parseErr != nil {
+			continue
+		}
+		return applyZoneOffset(parsed).UTC(), dateLayout.name, nil
+	}
+	return time.Time{}, "", ErrorUnparseableDate
+}
+
+func applyZoneOffset(parsed time.Time) time.Time {
+	zoneName, offset := parsed.Zone()
+	if offset != 0 {
+		return parsed
+	}
+	knownOffset, found := zoneOffsets[strings.ToUpper(zoneName)]
+	if !found {
+		return parsed
+	}
+	return time.Date(parsed.Year(), parsed.Month(), parsed.Day(),
+		parsed.Hour(), parsed.Minute(), parsed.Second(), parsed.Nanosecond(),
+		time.FixedZone(zoneName, knownOffset))
+}
@@ -0,0 +1,117 @@
+package date_parsing
+
+import (
+	"testing"
+	"time"
+
+	testutils "github.com/sohWenMing/aggregator/test_utils"
+)
+
+func TestParseDate(t *testing.T) {
+	type testStruct struct {
+		name           string
+		input          string
+		isErrExpected  bool
+		expectedDate   time.Time
+		expectedLayout string
+	}
+
+	tests := []testStruct{
+		{
+			"RFC1123Z",
+			"Sun, 08 Jan 2023 00:00:00 +0000",
+			false,
+			time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC),
+			"RFC1123Z",
+		},
+		{
+			"RFC1123Z single digit day",
+			"Sun, 8 Jan 2023 08:00:00 +0800",
+			false,
+			time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC),
+			"RFC1123Z",
+		},
+		{
+			"RFC1123 with zone name",
+			"Sat, 07 Jan 2023 19:00:00 EST",
+			false,
+			time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC),
+			"RFC1123",
+		},
+		{
+			"RFC1123 with GMT",
+			"Sun, 08 Jan 2023 00:00:00 GMT",
+			false,
+			time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC),
+			"RFC1123",
+		},
+		{
+			"atom RFC3339",
+			"2023-01-08T08:00:00+08:00",
+			false,
+			time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC),
+			"RFC3339",
+		},
+		{
+			"atom RFC3339 with fractional seconds",
+			"2023-01-08T00:00:00.123Z",
+			false,
+			time.Date(2023, 1, 8, 0, 0, 0, 123000000, time.UTC),
+			"RFC3339",
+		},
+		{
+			"ISO8601 without seconds",
+			"2023-01-08T00:00Z",
+			false,
+			time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC),
+			"ISO8601 no seconds",
+		},
+		{
+			"dublin core date only",
+			"2023-01-08",
+			false,
+			time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC),
+			"W3CDTF date",
+		},
+		{
+			"surrounding and repeated whitespace",
+			"\n  Sun,  08 Jan 2023 00:00:00 +0000 \n",
+			false,
+			time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC),
+			"RFC1123Z",
+		},
+		{
+			"empty string",
+			"",
+			true,
+			time.Time{},
+			"",
+		},
+		{
+			"garbage",
+			"last tuesday",
+			true,
+			time.Time{},
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, layoutName, err := ParseDate(test.input)
+			switch test.isErrExpected {
+			case true:
+				testutils.AssertHasErr(err, t)
+			case false:
+				testutils.AssertNoErr(err, t)
+				if !got.Equal(test.expectedDate) {
+					t.Errorf("got: %v\nwant: %v\n", got, test.expectedDate)
+				}
+				if got.Location() != time.UTC {
+					t.Errorf("expected date to be normalised to UTC, got location %v\n", got.Location())
+				}
+				testutils.AssertStrings(layoutName, test.expectedLayout, t)
+			}
+		})
+	}
+}
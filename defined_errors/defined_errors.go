@@ -3,12 +3,19 @@ package definederrors
 import "errors"
 
 var (
-	ErrorInput             = errors.New("input was invalid")
-	ErrorNoArgs            = errors.New("no arguments were passed in")
-	ErrorWrongNumArgs      = errors.New("wrong number of arguments passed in ")
-	ErrorHandlerNotExist   = errors.New("handler does not exist")
-	ErrorNilPointer        = errors.New("pointer is nil")
-	ErrorUserAlreadyExists = errors.New("user already exists in database")
-	ErrorUserNotFound      = errors.New("user could not be retrieved")
-	ErrorDatabaseErr       = errors.New("generic database error")
+	ErrorInput              = errors.New("input was invalid")
+	ErrorNoArgs             = errors.New("no arguments were passed in")
+	ErrorWrongNumArgs       = errors.New("wrong number of arguments passed in ")
+	ErrorHandlerNotExist    = errors.New("handler does not exist")
+	ErrorNilPointer         = errors.New("pointer is nil")
+	ErrorUserAlreadyExists  = errors.New("user already exists in database")
+	ErrorUserNotFound       = errors.New("user could not be retrieved")
+	ErrorDatabaseErr        = errors.New("generic database error")
+	ErrorFeedNotModified    = errors.New("feed has not been modified since it was last fetched")
+	ErrorUnexpectedStatus   = errors.New("unexpected http status code")
+	ErrorInvalidCABundle    = errors.New("ca bundle did not contain any valid certificates")
+	ErrorResponseTooLarge   = errors.New("response body is larger than the maximum allowed size")
+	ErrorNoFeedsFound       = errors.New("no feeds were found")
+	ErrorMultipleFeedsFound = errors.New("more than one feed was found")
+	ErrorAlreadyFollowing   = errors.New("user is already following the feed")
 )
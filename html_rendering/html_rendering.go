@@ -0,0 +1,301 @@
+package html_rendering
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Render converts html into text for the terminal. block elements become paragraphs separated by blank lines,
+// list items become bullets or numbers, emphasis is marked with * and _, and links are replaced by numbered
+// references like [1], which are listed after the text. lines are wrapped at width columns, or not at all if
+// width is zero or less. the html is expected to have been sanitized already
+func Render(input string, width int) (rendered string, err error) {
+	nodes, err := html.ParseFragment(strings.NewReader(input), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return "", err
+	}
+	r := renderer{width: width}
+	for _, node := range nodes {
+		r.renderNode(node)
+	}
+	r.flush()
+	return r.String(), nil
+}
+
+type block struct {
+	text string
+	//consecutive list items are only separated by a line break, rather than a blank line
+	tight bool
+}
+
+type listState struct {
+	ordered bool
+	index   int
+}
+
+type renderer struct {
+	width  int
+	blocks []block
+	links  []string
+
+	inline strings.Builder
+	//prefixes are added to the start of every line, for quotes and the indentation of list items
+	prefixes []string
+	//bullet replaces the last prefix on the first line of a list item
+	bullet     string
+	lists      []listState
+	preDepth   int
+	prevInList bool
+}
+
+func (r *renderer) renderNode(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		r.writeText(node.Data)
+		return
+	case html.ElementNode:
+	default:
+		r.renderChildren(node)
+		return
+	}
+
+	switch node.DataAtom {
+	case atom.Script, atom.Style, atom.Head:
+		return
+	case atom.Br:
+		r.inline.WriteString("\n")
+	case atom.Hr:
+		r.flush()
+		r.addBlock(strings.Repeat("-", r.ruleWidth()))
+	case atom.Img:
+		alt := strings.TrimSpace(getAttr(node, "alt"))
+		if alt == "" {
+			r.inline.WriteString("[image]")
+		} else {
+			fmt.Fprintf(&r.inline, "[image: %s]", alt)
+		}
+	case atom.Em, atom.I:
+		r.wrapInline(node, "_")
+	case atom.Strong, atom.B:
+		r.wrapInline(node, "*")
+	case atom.Code:
+		if r.preDepth > 0 {
+			r.renderChildren(node)
+		} else {
+			r.wrapInline(node, "`")
+		}
+	case atom.A:
+		r.renderChildren(node)
+		href := strings.TrimSpace(getAttr(node, "href"))
+		if href != "" && !strings.HasPrefix(href, "#") {
+			r.links = append(r.links, href)
+			fmt.Fprintf(&r.inline, "[%d]", len(r.links))
+		}
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		r.flush()
+		level := int(node.Data[1] - '0')
+		r.inline.WriteString(strings.Repeat("#", level) + " ")
+		r.renderChildren(node)
+		r.flush()
+	case atom.Blockquote:
+		r.flush()
+		r.prefixes = append(r.prefixes, "> ")
+		r.renderChildren(node)
+		r.flush()
+		r.prefixes = r.prefixes[:len(r.prefixes)-1]
+	case atom.Pre:
+		r.flush()
+		r.preDepth++
+		r.renderChildren(node)
+		r.preDepth--
+		r.flushPre()
+	case atom.Ul, atom.Ol:
+		r.flush()
+		r.lists = append(r.lists, listState{ordered: node.DataAtom == atom.Ol})
+		r.renderChildren(node)
+		r.flush()
+		r.lists = r.lists[:len(r.lists)-1]
+	case atom.Li:
+		r.flush()
+		marker := "- "
+		if len(r.lists) > 0 && r.lists[len(r.lists)-1].ordered {
+			r.lists[len(r.lists)-1].index++
+			marker = fmt.Sprintf("%d. ", r.lists[len(r.lists)-1].index)
+		}
+		r.bullet = marker
+		r.prefixes = append(r.prefixes, strings.Repeat(" ", len(marker)))
+		r.renderChildren(node)
+		r.flush()
+		r.bullet = ""
+		r.prefixes = r.prefixes[:len(r.prefixes)-1]
+	case atom.Td, atom.Th:
+		r.inline.WriteString(" ")
+		r.renderChildren(node)
+		r.inline.WriteString(" ")
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Figure, atom.Figcaption,
+		atom.Table, atom.Tr, atom.Dl, atom.Dt, atom.Dd:
+		r.flush()
+		r.renderChildren(node)
+		r.flush()
+	default:
+		r.renderChildren(node)
+	}
+}
+
+func (r *renderer) renderChildren(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		r.renderNode(child)
+	}
+}
+
+// surrounds the text of the node with marker, leaving it out if the node has no text
+func (r *renderer) wrapInline(node *html.Node, marker string) {
+	before := r.inline.Len()
+	r.inline.WriteString(marker)
+	r.renderChildren(node)
+	if strings.TrimSpace(r.inline.String()[before+len(marker):]) == "" {
+		text := r.inline.String()[:before]
+		r.inline.Reset()
+		r.inline.WriteString(text)
+		return
+	}
+	r.inline.WriteString(marker)
+}
+
+func (r *renderer) writeText(text string) {
+	if r.preDepth > 0 {
+		r.inline.WriteString(text)
+		return
+	}
+	//outside of pre, line breaks in the source are just whitespace
+	r.inline.WriteString(strings.ReplaceAll(text, "\n", " "))
+}
+
+// turns the inline text collected so far into a block, wrapped and prefixed
+func (r *renderer) flush() {
+	text := r.inline.String()
+	r.inline.Reset()
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	firstPrefix, restPrefix := r.linePrefixes()
+	lines := []string{}
+	for i, hardLine := range strings.Split(text, "\n") {
+		if strings.TrimSpace(hardLine) == "" {
+			continue
+		}
+		prefix := restPrefix
+		if i == 0 || len(lines) == 0 {
+			prefix = firstPrefix
+		}
+		lines = append(lines, wrap(hardLine, r.width, prefix, restPrefix)...)
+	}
+	r.addBlock(strings.Join(lines, "\n"))
+}
+
+// preformatted text keeps its whitespace and isn't wrapped, it's only indented
+func (r *renderer) flushPre() {
+	text := strings.Trim(r.inline.String(), "\n")
+	r.inline.Reset()
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	_, restPrefix := r.linePrefixes()
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(restPrefix+"    "+line, " ")
+	}
+	r.addBlock(strings.Join(lines, "\n"))
+}
+
+func (r *renderer) linePrefixes() (firstPrefix string, restPrefix string) {
+	restPrefix = strings.Join(r.prefixes, "")
+	firstPrefix = restPrefix
+	if r.bullet != "" {
+		firstPrefix = strings.Join(r.prefixes[:len(r.prefixes)-1], "") + r.bullet
+		r.bullet = ""
+	}
+	return firstPrefix, restPrefix
+}
+
+func (r *renderer) addBlock(text string) {
+	inList := len(r.lists) > 0
+	r.blocks = append(r.blocks, block{text: text, tight: inList && r.prevInList})
+	r.prevInList = inList
+}
+
+func (r *renderer) ruleWidth() int {
+	if r.width <= 0 || r.width > 40 {
+		return 40
+	}
+	return r.width
+}
+
+func (r *renderer) String() string {
+	output := strings.Builder{}
+	for i, block := range r.blocks {
+		if i > 0 {
+			if block.tight {
+				output.WriteString("\n")
+			} else {
+				output.WriteString("\n\n")
+			}
+		}
+		output.WriteString(block.text)
+	}
+	if len(r.links) > 0 {
+		if output.Len() > 0 {
+			output.WriteString("\n\n")
+		}
+		for i, link := range r.links {
+			if i > 0 {
+				output.WriteString("\n")
+			}
+			fmt.Fprintf(&output, "[%d] %s", i+1, link)
+		}
+	}
+	return output.String()
+}
+
+// wraps the words of text into lines of at most width characters including the prefix. a word that is longer
+// than a line is put on a line of its own rather than being broken up
+func wrap(text string, width int, firstPrefix string, restPrefix string) (lines []string) {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+	line := strings.Builder{}
+	line.WriteString(firstPrefix + words[0])
+	lineLength := utf8.RuneCountInString(line.String())
+	for _, word := range words[1:] {
+		wordLength := utf8.RuneCountInString(word)
+		if width > 0 && lineLength+1+wordLength > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			line.WriteString(restPrefix + word)
+			lineLength = utf8.RuneCountInString(restPrefix) + wordLength
+			continue
+		}
+		line.WriteString(" " + word)
+		lineLength += 1 + wordLength
+	}
+	return append(lines, line.String())
+}
+
+func getAttr(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package html_rendering
+
+import (
+	"testing"
+
+	testutils "github.com/sohWenMing/aggregator/test_utils"
+)
+
+func TestRender(t *testing.T) {
+	type testStruct struct {
+		name     string
+		input    string
+		width    int
+		expected string
+	}
+	tests := []testStruct{
+		{
+			"paragraphs",
+			"<p>First paragraph.</p>\n<p>Second\nparagraph.</p>",
+			80,
+			"First paragraph.\n\nSecond paragraph.",
+		},
+		{
+			"emphasis and code",
+			"<p>Some <em>emphasised</em>, <strong>strong</strong> and <code>code</code> text.</p>",
+			80,
+			"Some _emphasised_, *strong* and `code` text.",
+		},
+		{
+			"numbered link references",
+			`<p>Read <a href="https://example.com/one">the first</a> and <a href="https://example.com/two">second</a> posts.</p>`,
+			80,
+			"Read the first[1] and second[2] posts.\n\n[1] https://example.com/one\n[2] https://example.com/two",
+		},
+		{
+			"bullet list",
+			"<p>Before</p><ul><li>one</li><li>two</li></ul><p>After</p>",
+			80,
+			"Before\n\n- one\n- two\n\nAfter",
+		},
+		{
+			"nested ordered list",
+			"<ol><li>first<ul><li>inner</li></ul></li><li>second</li></ol>",
+			80,
+			"1. first\n   - inner\n2. second",
+		},
+		{
+			"wrapping",
+			"<p>the quick brown fox jumps over the lazy dog</p>",
+			20,
+			"the quick brown fox\njumps over the lazy\ndog",
+		},
+		{
+			"wrapped list item is indented",
+			"<ul><li>the quick brown fox jumps over the lazy dog</li></ul>",
+			20,
+			"- the quick brown\n  fox jumps over the\n  lazy dog",
+		},
+		{
+			"no wrapping",
+			"<p>the quick brown fox jumps over the lazy dog</p>",
+			0,
+			"the quick brown fox jumps over the lazy dog",
+		},
+		{
+			"blockquote and heading",
+			"<h2>Title</h2><blockquote><p>quoted text</p></blockquote>",
+			80,
+			"## Title\n\n> quoted text",
+		},
+		{
+			"preformatted",
+			"<pre><code>func main() {\n\tfmt.Println()\n}</code></pre>",
+			80,
+			"    func main() {\n    \tfmt.Println()\n    }",
+		},
+		{
+			"line break and image",
+			`<p>line one<br>line two <img src="https://example.com/a.png" alt="a chart"></p>`,
+			80,
+			"line one\nline two [image: a chart]",
+		},
+		{
+			"plain text",
+			"just some text & more",
+			80,
+			"just some text & more",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Render(test.input, test.width)
+			testutils.AssertNoErr(err, t)
+			testutils.AssertStrings(got, test.expected, t)
+		})
+	}
+}
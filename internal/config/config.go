@@ -5,15 +5,38 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	definederrors "github.com/sohWenMing/aggregator/defined_errors"
 )
 
+const (
+	DefaultFetchTimeout          = 10 * time.Second
+	DefaultDialTimeout           = 30 * time.Second
+	DefaultTLSHandshakeTimeout   = 10 * time.Second
+	DefaultResponseHeaderTimeout = 10 * time.Second
+	DefaultUserAgent             = "gator"
+	DefaultMaxBodyBytes          = 20 << 20
+	DefaultContentPolicy         = "ugc"
+	DefaultRenderWidth           = 80
+)
+
 type Config struct {
-	DbUrl           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
-	CurrentUser     struct {
+	DbUrl                 string `json:"db_url"`
+	CurrentUserName       string `json:"current_user_name"`
+	FetchTimeout          string `json:"fetch_timeout,omitempty"`
+	DialTimeout           string `json:"dial_timeout,omitempty"`
+	TLSHandshakeTimeout   string `json:"tls_handshake_timeout,omitempty"`
+	ResponseHeaderTimeout string `json:"response_header_timeout,omitempty"`
+	UserAgent             string `json:"user_agent,omitempty"`
+	ProxyURL              string `json:"proxy_url,omitempty"`
+	CABundlePath          string `json:"ca_bundle_path,omitempty"`
+	MaxBodyBytes          int64  `json:"max_body_bytes,omitempty"`
+	ContentPolicy         string `json:"content_policy,omitempty"`
+	RenderWidth           int    `json:"render_width,omitempty"`
+	CurrentUser           struct {
 		ID   uuid.UUID
 		Name string
 	}
@@ -40,6 +63,72 @@ func (c *Config) SetUser(username string, w io.Writer) (err error) {
 	return nil
 }
 
+// GetFetchTimeout returns the timeout for requests to feeds, parsed from a duration string such as "30s".
+// DefaultFetchTimeout is used if it hasn't been set or can't be parsed
+func (c *Config) GetFetchTimeout() time.Duration {
+	return parseDurationOrDefault(c.FetchTimeout, DefaultFetchTimeout)
+}
+
+// GetDialTimeout returns how long opening a connection to a feed's host can take
+func (c *Config) GetDialTimeout() time.Duration {
+	return parseDurationOrDefault(c.DialTimeout, DefaultDialTimeout)
+}
+
+// GetTLSHandshakeTimeout returns how long the TLS handshake with a feed's host can take
+func (c *Config) GetTLSHandshakeTimeout() time.Duration {
+	return parseDurationOrDefault(c.TLSHandshakeTimeout, DefaultTLSHandshakeTimeout)
+}
+
+// GetResponseHeaderTimeout returns how long to wait for a feed's response headers once the request has been sent
+func (c *Config) GetResponseHeaderTimeout() time.Duration {
+	return parseDurationOrDefault(c.ResponseHeaderTimeout, DefaultResponseHeaderTimeout)
+}
+
+// GetUserAgent returns the user agent sent with requests to feeds. feed owners appreciate one that says
+// what the program is and how to get in contact, e.g. "gator/1.0 (+https://example.com/contact)"
+func (c *Config) GetUserAgent() string {
+	userAgent := strings.TrimSpace(c.UserAgent)
+	if userAgent == "" {
+		return DefaultUserAgent
+	}
+	return userAgent
+}
+
+// GetMaxBodyBytes returns the most bytes that will be read from a feed's response before the fetch is abandoned
+func (c *Config) GetMaxBodyBytes() int64 {
+	if c.MaxBodyBytes <= 0 {
+		return DefaultMaxBodyBytes
+	}
+	return c.MaxBodyBytes
+}
+
+// GetContentPolicy returns the name of the policy that the full content of posts is sanitized with,
+// either "strict" or "ugc". see rss_parsing.SanitizeContent
+func (c *Config) GetContentPolicy() string {
+	contentPolicy := strings.ToLower(strings.TrimSpace(c.ContentPolicy))
+	if contentPolicy == "" {
+		return DefaultContentPolicy
+	}
+	return contentPolicy
+}
+
+// GetRenderWidth returns the number of columns that the content of posts is wrapped to when it's shown.
+// a negative width turns wrapping off
+func (c *Config) GetRenderWidth() int {
+	if c.RenderWidth == 0 {
+		return DefaultRenderWidth
+	}
+	return c.RenderWidth
+}
+
+func parseDurationOrDefault(input string, defaultDuration time.Duration) time.Duration {
+	duration, err := time.ParseDuration(input)
+	if err != nil || duration <= 0 {
+		return defaultDuration
+	}
+	return duration
+}
+
 func Read() (config *Config, err error) {
 	returnedConfig := Config{}
 	file, err := openConfigFile()
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"testing"
+	"time"
 
 	definederrors "github.com/sohWenMing/aggregator/defined_errors"
 	errorutils "github.com/sohWenMing/aggregator/error_utils"
@@ -60,3 +61,83 @@ func TestSetUser(t *testing.T) {
 		})
 	}
 }
+
+func TestGetFetchTimeout(t *testing.T) {
+	type testStruct struct {
+		name         string
+		fetchTimeout string
+		expected     time.Duration
+	}
+	tests := []testStruct{
+		{"not set", "", DefaultFetchTimeout},
+		{"valid duration", "30s", 30 * time.Second},
+		{"invalid duration", "thirty seconds", DefaultFetchTimeout},
+		{"negative duration", "-5s", DefaultFetchTimeout},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{FetchTimeout: test.fetchTimeout}
+			got := config.GetFetchTimeout()
+			if got != test.expected {
+				t.Errorf("got: %v\nwant: %v\n", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestGetUserAgent(t *testing.T) {
+	type testStruct struct {
+		name      string
+		userAgent string
+		expected  string
+	}
+	tests := []testStruct{
+		{"not set", "", DefaultUserAgent},
+		{"only whitespace", "   ", DefaultUserAgent},
+		{"set", "gator/1.0 (+https://example.com/contact)", "gator/1.0 (+https://example.com/contact)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{UserAgent: test.userAgent}
+			testUtils.AssertStrings(config.GetUserAgent(), test.expected, t)
+		})
+	}
+}
+
+func TestGetContentPolicy(t *testing.T) {
+	type testStruct struct {
+		name          string
+		contentPolicy string
+		expected      string
+	}
+	tests := []testStruct{
+		{"not set", "", DefaultContentPolicy},
+		{"set", "strict", "strict"},
+		{"mixed case", " UGC ", "ugc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{ContentPolicy: test.contentPolicy}
+			testUtils.AssertStrings(config.GetContentPolicy(), test.expected, t)
+		})
+	}
+}
+
+func TestGetRenderWidth(t *testing.T) {
+	type testStruct struct {
+		name        string
+		renderWidth int
+		expected    int
+	}
+	tests := []testStruct{
+		{"not set", 0, DefaultRenderWidth},
+		{"set", 100, 100},
+		{"wrapping turned off", -1, -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Config{RenderWidth: test.renderWidth}
+			testUtils.AssertInts(config.GetRenderWidth(), test.expected, t)
+		})
+	}
+}
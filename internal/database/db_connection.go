@@ -10,6 +10,7 @@ import (
 
 type State struct {
 	Db     *Queries
+	Conn   *sql.DB
 	Cfg    *config.Config
 	Client *http.Client
 }
@@ -19,6 +20,10 @@ func CreateDBConnection() (state *State, err error) {
 	if err != nil {
 		return nil, err
 	}
+	client, err := NewHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
 	dbURL := config.DbUrl
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
@@ -28,8 +33,9 @@ func CreateDBConnection() (state *State, err error) {
 
 	newState := State{
 		Db:     queries,
+		Conn:   db,
 		Cfg:    config,
-		Client: &http.Client{},
+		Client: client,
 	}
 	return &newState, nil
 
@@ -7,24 +7,106 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimNextFeedsToFetch = `-- name: ClaimNextFeedsToFetch :many
+UPDATE feeds
+SET updated_at = $1, last_fetched_at = $1, next_fetch_at = $3
+WHERE feeds.id IN (
+    SELECT feeds.id
+      FROM feeds
+     WHERE feeds.disabled_at IS NULL
+       AND (feeds.next_eligible_at IS NULL OR feeds.next_eligible_at <= $1)
+       AND (feeds.next_allowed_fetch_at IS NULL OR feeds.next_allowed_fetch_at <= $1)
+       AND (feeds.next_fetch_at IS NULL OR feeds.next_fetch_at <= $1)
+     ORDER BY feeds.next_fetch_at NULLS FIRST
+     LIMIT $2
+       FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, last_http_status, next_eligible_at, consecutive_not_found, disabled_at, disabled_reason, ttl_minutes, skip_hours, skip_days, sy_update_period, sy_update_frequency, next_allowed_fetch_at, next_fetch_at, site_link, description, language, image_url, generator
+`
+
+type ClaimNextFeedsToFetchParams struct {
+	UpdatedAt   time.Time
+	Limit       int32
+	NextFetchAt sql.NullTime
+}
+
+func (q *Queries) ClaimNextFeedsToFetch(ctx context.Context, arg ClaimNextFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimNextFeedsToFetch, arg.UpdatedAt, arg.Limit, arg.NextFetchAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastHttpStatus,
+			&i.NextEligibleAt,
+			&i.ConsecutiveNotFound,
+			&i.DisabledAt,
+			&i.DisabledReason,
+			&i.TtlMinutes,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.SyUpdatePeriod,
+			&i.SyUpdateFrequency,
+			&i.NextAllowedFetchAt,
+			&i.NextFetchAt,
+			&i.SiteLink,
+			&i.Description,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
-VALUES($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, site_link, description, language, image_url, generator)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (url) DO NOTHING
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, last_http_status, next_eligible_at, consecutive_not_found, disabled_at, disabled_reason, ttl_minutes, skip_hours, skip_days, sy_update_period, sy_update_frequency, next_allowed_fetch_at, next_fetch_at, site_link, description, language, image_url, generator
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Name        string
+	Url         string
+	UserID      uuid.UUID
+	SiteLink    sql.NullString
+	Description sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+	Generator   sql.NullString
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -35,6 +117,11 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Name,
 		arg.Url,
 		arg.UserID,
+		arg.SiteLink,
+		arg.Description,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
 	)
 	var i Feed
 	err := row.Scan(
@@ -45,6 +132,27 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastHttpStatus,
+		&i.NextEligibleAt,
+		&i.ConsecutiveNotFound,
+		&i.DisabledAt,
+		&i.DisabledReason,
+		&i.TtlMinutes,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.SyUpdatePeriod,
+		&i.SyUpdateFrequency,
+		&i.NextAllowedFetchAt,
+		&i.NextFetchAt,
+		&i.SiteLink,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
 	)
 	return i, err
 }
@@ -128,6 +236,50 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 	return err
 }
 
+const disableFeed = `-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = $1, disabled_reason = $2, updated_at = $3
+WHERE feeds.id = $4
+`
+
+type DisableFeedParams struct {
+	DisabledAt     sql.NullTime
+	DisabledReason sql.NullString
+	UpdatedAt      time.Time
+	ID             uuid.UUID
+}
+
+func (q *Queries) DisableFeed(ctx context.Context, arg DisableFeedParams) error {
+	_, err := q.db.ExecContext(ctx, disableFeed,
+		arg.DisabledAt,
+		arg.DisabledReason,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const enableFeed = `-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled_at = NULL,
+    disabled_reason = NULL,
+    consecutive_failures = 0,
+    consecutive_not_found = 0,
+    next_eligible_at = NULL,
+    updated_at = $1
+WHERE feeds.id = $2
+`
+
+type EnableFeedParams struct {
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) EnableFeed(ctx context.Context, arg EnableFeedParams) error {
+	_, err := q.db.ExecContext(ctx, enableFeed, arg.UpdatedAt, arg.ID)
+	return err
+}
+
 const getFeedFollowForUser = `-- name: GetFeedFollowForUser :many
 SELECT feeds.name as feed_name, feeds.url as feed_url, users.name as user_name
   FROM feed_follows
@@ -181,16 +333,22 @@ func (q *Queries) GetFeedIdByURL(ctx context.Context, url string) (uuid.UUID, er
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT feeds.name AS FeedName, feeds.url AS FeedUrl, users.name as UserName
+SELECT feeds.name AS FeedName, feeds.url AS FeedUrl, users.name as UserName,
+       feeds.site_link, feeds.description, feeds.language, feeds.image_url, feeds.generator
   FROM feeds
   JOIN users
     ON feeds.user_id = users.id
 `
 
 type GetFeedsRow struct {
-	Feedname string
-	Feedurl  string
-	Username string
+	Feedname    string
+	Feedurl     string
+	Username    string
+	SiteLink    sql.NullString
+	Description sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+	Generator   sql.NullString
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -202,7 +360,16 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Feedname, &i.Feedurl, &i.Username); err != nil {
+		if err := rows.Scan(
+			&i.Feedname,
+			&i.Feedurl,
+			&i.Username,
+			&i.SiteLink,
+			&i.Description,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -216,26 +383,65 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at
+const getFeedsWithErrors = `-- name: GetFeedsWithErrors :many
+SELECT feeds.name AS feed_name,
+       feeds.url AS feed_url,
+       feeds.consecutive_failures,
+       feeds.last_error,
+       feeds.last_http_status,
+       feeds.last_fetched_at,
+       feeds.next_eligible_at,
+       feeds.disabled_at,
+       feeds.disabled_reason
   FROM feeds
-  ORDER BY feeds.last_fetched_at NULLS FIRST
-  LIMIT 1
+ WHERE feeds.consecutive_failures > 0
+    OR feeds.disabled_at IS NOT NULL
+ ORDER BY feeds.consecutive_failures DESC, feeds.name
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+type GetFeedsWithErrorsRow struct {
+	FeedName            string
+	FeedUrl             string
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastHttpStatus      sql.NullInt32
+	LastFetchedAt       sql.NullTime
+	NextEligibleAt      sql.NullTime
+	DisabledAt          sql.NullTime
+	DisabledReason      sql.NullString
+}
+
+func (q *Queries) GetFeedsWithErrors(ctx context.Context) ([]GetFeedsWithErrorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsWithErrors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedsWithErrorsRow
+	for rows.Next() {
+		var i GetFeedsWithErrorsRow
+		if err := rows.Scan(
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastHttpStatus,
+			&i.LastFetchedAt,
+			&i.NextEligibleAt,
+			&i.DisabledAt,
+			&i.DisabledReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const markFetchedFeed = `-- name: MarkFetchedFeed :exec
@@ -254,6 +460,59 @@ func (q *Queries) MarkFetchedFeed(ctx context.Context, arg MarkFetchedFeedParams
 	return err
 }
 
+const recordFeedFetchFailure = `-- name: RecordFeedFetchFailure :exec
+UPDATE feeds
+SET consecutive_failures = feeds.consecutive_failures + 1,
+    consecutive_not_found = CASE WHEN $2 = 404 THEN feeds.consecutive_not_found + 1 ELSE 0 END,
+    last_error = $1,
+    last_http_status = $2,
+    next_eligible_at = $3,
+    next_fetch_at = $3,
+    updated_at = $4
+WHERE feeds.id = $5
+`
+
+type RecordFeedFetchFailureParams struct {
+	LastError      sql.NullString
+	LastHttpStatus sql.NullInt32
+	NextEligibleAt sql.NullTime
+	UpdatedAt      time.Time
+	ID             uuid.UUID
+}
+
+func (q *Queries) RecordFeedFetchFailure(ctx context.Context, arg RecordFeedFetchFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFetchFailure,
+		arg.LastError,
+		arg.LastHttpStatus,
+		arg.NextEligibleAt,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const recordFeedFetchSuccess = `-- name: RecordFeedFetchSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    consecutive_not_found = 0,
+    last_error = NULL,
+    last_http_status = $1,
+    next_eligible_at = NULL,
+    updated_at = $2
+WHERE feeds.id = $3
+`
+
+type RecordFeedFetchSuccessParams struct {
+	LastHttpStatus sql.NullInt32
+	UpdatedAt      time.Time
+	ID             uuid.UUID
+}
+
+func (q *Queries) RecordFeedFetchSuccess(ctx context.Context, arg RecordFeedFetchSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFetchSuccess, arg.LastHttpStatus, arg.UpdatedAt, arg.ID)
+	return err
+}
+
 const resetFeeds = `-- name: ResetFeeds :exec
 DELETE from feeds
 `
@@ -262,3 +521,114 @@ func (q *Queries) ResetFeeds(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetFeeds)
 	return err
 }
+
+const setFeedNextAllowedFetch = `-- name: SetFeedNextAllowedFetch :exec
+UPDATE feeds
+SET next_allowed_fetch_at = $1, updated_at = $2
+WHERE feeds.id = $3
+`
+
+type SetFeedNextAllowedFetchParams struct {
+	NextAllowedFetchAt sql.NullTime
+	UpdatedAt          time.Time
+	ID                 uuid.UUID
+}
+
+func (q *Queries) SetFeedNextAllowedFetch(ctx context.Context, arg SetFeedNextAllowedFetchParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextAllowedFetch, arg.NextAllowedFetchAt, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setFeedNextFetch = `-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = $1, updated_at = $2
+WHERE feeds.id = $3
+`
+
+type SetFeedNextFetchParams struct {
+	NextFetchAt sql.NullTime
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) SetFeedNextFetch(ctx context.Context, arg SetFeedNextFetchParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetch, arg.NextFetchAt, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const updateFeedCacheHeaders = `-- name: UpdateFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $1, last_modified = $2, updated_at = $3
+WHERE feeds.id = $4
+`
+
+type UpdateFeedCacheHeadersParams struct {
+	Etag         sql.NullString
+	LastModified sql.NullString
+	UpdatedAt    time.Time
+	ID           uuid.UUID
+}
+
+func (q *Queries) UpdateFeedCacheHeaders(ctx context.Context, arg UpdateFeedCacheHeadersParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheHeaders,
+		arg.Etag,
+		arg.LastModified,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedRefreshHints = `-- name: UpdateFeedRefreshHints :exec
+UPDATE feeds
+SET ttl_minutes = $1,
+    skip_hours = $2,
+    skip_days = $3,
+    sy_update_period = $4,
+    sy_update_frequency = $5,
+    next_allowed_fetch_at = $6,
+    updated_at = $7
+WHERE feeds.id = $8
+`
+
+type UpdateFeedRefreshHintsParams struct {
+	TtlMinutes         sql.NullInt32
+	SkipHours          sql.NullString
+	SkipDays           sql.NullString
+	SyUpdatePeriod     sql.NullString
+	SyUpdateFrequency  sql.NullInt32
+	NextAllowedFetchAt sql.NullTime
+	UpdatedAt          time.Time
+	ID                 uuid.UUID
+}
+
+func (q *Queries) UpdateFeedRefreshHints(ctx context.Context, arg UpdateFeedRefreshHintsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedRefreshHints,
+		arg.TtlMinutes,
+		arg.SkipHours,
+		arg.SkipDays,
+		arg.SyUpdatePeriod,
+		arg.SyUpdateFrequency,
+		arg.NextAllowedFetchAt,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $1, updated_at = $2
+WHERE feeds.id = $3
+`
+
+type UpdateFeedURLParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.Url, arg.UpdatedAt, arg.ID)
+	return err
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	definederrors "github.com/sohWenMing/aggregator/defined_errors"
+	"github.com/sohWenMing/aggregator/internal/config"
+)
+
+// NewHTTPClient builds the client used to fetch feeds from the proxy, ca bundle, user agent and timeouts in the config.
+// the overall time a request can take is left to the context of each request, see config.GetFetchTimeout
+func NewHTTPClient(cfg *config.Config) (client *http.Client, err error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.GetDialTimeout(),
+		KeepAlive: 30 * time.Second,
+	}
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = cfg.GetTLSHandshakeTimeout()
+	transport.ResponseHeaderTimeout = cfg.GetResponseHeaderTimeout()
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("proxy_url %q could not be parsed: %w", cfg.ProxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.CABundlePath != "" {
+		rootCAs, err := loadCABundle(cfg.CABundlePath)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: rootCAs}
+	}
+
+	return &http.Client{
+		Transport: &userAgentTransport{
+			userAgent: cfg.GetUserAgent(),
+			base:      transport,
+		},
+	}, nil
+}
+
+// the certificates in the bundle are added on top of the system's, so that feeds with publicly trusted
+// certificates can still be fetched when a corporate ca is needed for the rest
+func loadCABundle(path string) (pool *x509.CertPool, err error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ca_bundle_path %q could not be read: %w", path, err)
+	}
+	pool, err = x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, fmt.Errorf("ca_bundle_path %q: %w", path, definederrors.ErrorInvalidCABundle)
+	}
+	return pool, nil
+}
+
+// sets the user agent on every request that goes out through the client, redirects included
+type userAgentTransport struct {
+	userAgent string
+	base      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	//round trippers must not modify the request that was passed in
+	clonedReq := req.Clone(req.Context())
+	clonedReq.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(clonedReq)
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	definederrors "github.com/sohWenMing/aggregator/defined_errors"
+	"github.com/sohWenMing/aggregator/internal/config"
+	testutils "github.com/sohWenMing/aggregator/test_utils"
+)
+
+func TestNewHTTPClientUserAgent(t *testing.T) {
+	type testStruct struct {
+		name      string
+		userAgent string
+		expected  string
+	}
+	tests := []testStruct{
+		{"default user agent", "", config.DefaultUserAgent},
+		{"configured user agent", "gator/1.0 (+https://example.com/contact)", "gator/1.0 (+https://example.com/contact)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotUserAgent := ""
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserAgent = r.UserAgent()
+			}))
+			defer server.Close()
+
+			client, err := NewHTTPClient(&config.Config{UserAgent: test.userAgent})
+			testutils.AssertNoErr(err, t)
+			res, err := client.Get(server.URL)
+			testutils.AssertNoErr(err, t)
+			res.Body.Close()
+			testutils.AssertStrings(gotUserAgent, test.expected, t)
+		})
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	// the proxy receives the request with the full url of the feed as the request target
+	gotURL := ""
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	client, err := NewHTTPClient(&config.Config{ProxyURL: proxy.URL})
+	testutils.AssertNoErr(err, t)
+	res, err := client.Get("http://feeds.example.com/index.xml")
+	testutils.AssertNoErr(err, t)
+	res.Body.Close()
+	testutils.AssertStrings(gotURL, "http://feeds.example.com/index.xml", t)
+}
+
+func TestNewHTTPClientCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// the test server's certificate is self signed, so it can only be trusted through the bundle
+	bundlePath := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	testutils.AssertNoErr(os.WriteFile(bundlePath, certPEM, 0644), t)
+
+	client, err := NewHTTPClient(&config.Config{CABundlePath: bundlePath})
+	testutils.AssertNoErr(err, t)
+	res, err := client.Get(server.URL)
+	testutils.AssertNoErr(err, t)
+	res.Body.Close()
+
+	invalidPath := filepath.Join(t.TempDir(), "invalid.pem")
+	testutils.AssertNoErr(os.WriteFile(invalidPath, []byte("not a certificate"), 0644), t)
+	_, err = NewHTTPClient(&config.Config{CABundlePath: invalidPath})
+	testutils.AssertHasErr(err, t)
+	if !errors.Is(err, definederrors.ErrorInvalidCABundle) {
+		t.Errorf("got error: %v\nexpected error: %v", err, definederrors.ErrorInvalidCABundle)
+	}
+
+	_, err = NewHTTPClient(&config.Config{CABundlePath: filepath.Join(t.TempDir(), "missing.pem")})
+	testutils.AssertHasErr(err, t)
+}
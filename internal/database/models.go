@@ -11,14 +11,46 @@ import (
 	"github.com/google/uuid"
 )
 
+type Enclosure struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	PostID          uuid.UUID
+	Url             string
+	MimeType        sql.NullString
+	LengthBytes     sql.NullInt64
+	DurationSeconds sql.NullInt32
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	LastHttpStatus      sql.NullInt32
+	NextEligibleAt      sql.NullTime
+	ConsecutiveNotFound int32
+	DisabledAt          sql.NullTime
+	DisabledReason      sql.NullString
+	TtlMinutes          sql.NullInt32
+	SkipHours           sql.NullString
+	SkipDays            sql.NullString
+	SyUpdatePeriod      sql.NullString
+	SyUpdateFrequency   sql.NullInt32
+	NextAllowedFetchAt  sql.NullTime
+	NextFetchAt         sql.NullTime
+	SiteLink            sql.NullString
+	Description         sql.NullString
+	Language            sql.NullString
+	ImageUrl            sql.NullString
+	Generator           sql.NullString
 }
 
 type FeedFollow struct {
@@ -38,6 +70,8 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Guid        string
+	Content     sql.NullString
 }
 
 type User struct {
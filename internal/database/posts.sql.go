@@ -13,61 +13,38 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (
-    id, 
-    created_at, 
-    updated_at, 
-    title, 
-    url, 
-    description,
-    published_at,
-    feed_id)
-VALUES($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const getAveragePostInterval = `-- name: GetAveragePostInterval :one
+SELECT CAST(
+    COALESCE(
+        EXTRACT(EPOCH FROM MAX(recent_posts.published_at) - MIN(recent_posts.published_at))
+            / NULLIF(COUNT(*) - 1, 0),
+        0
+    ) AS DOUBLE PRECISION
+) AS average_interval_seconds
+  FROM (
+    SELECT posts.published_at
+      FROM posts
+     WHERE posts.feed_id = $1
+       AND posts.published_at IS NOT NULL
+     ORDER BY posts.published_at DESC
+     LIMIT 20
+  ) AS recent_posts
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
-}
-
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
-	var i Post
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Title,
-		&i.Url,
-		&i.Description,
-		&i.PublishedAt,
-		&i.FeedID,
-	)
-	return i, err
+func (q *Queries) GetAveragePostInterval(ctx context.Context, feedID uuid.UUID) (float64, error) {
+	row := q.db.QueryRowContext(ctx, getAveragePostInterval, feedID)
+	var average_interval_seconds float64
+	err := row.Scan(&average_interval_seconds)
+	return average_interval_seconds, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
 SELECT 
+    posts.id AS post_id,
     posts.title AS post_title,
     posts.url AS post_url,
     posts.description AS post_description,
+    posts.content AS post_content,
     posts.published_at AS post_published_on,
     feeds.name AS feed_name
   FROM users
@@ -88,9 +65,11 @@ type GetPostsForUserParams struct {
 }
 
 type GetPostsForUserRow struct {
+	PostID          uuid.UUID
 	PostTitle       string
 	PostUrl         string
 	PostDescription sql.NullString
+	PostContent     sql.NullString
 	PostPublishedOn sql.NullTime
 	FeedName        string
 }
@@ -105,9 +84,11 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	for rows.Next() {
 		var i GetPostsForUserRow
 		if err := rows.Scan(
+			&i.PostID,
 			&i.PostTitle,
 			&i.PostUrl,
 			&i.PostDescription,
+			&i.PostContent,
 			&i.PostPublishedOn,
 			&i.FeedName,
 		); err != nil {
@@ -123,3 +104,68 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO posts (
+    id, 
+    created_at, 
+    updated_at, 
+    title, 
+    url, 
+    description,
+    published_at,
+    feed_id,
+    guid,
+    content)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    published_at = EXCLUDED.published_at,
+    content = EXCLUDED.content
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content
+`
+
+type UpsertPostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Guid        string
+	Content     sql.NullString
+}
+
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Guid,
+		arg.Content,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.Content,
+	)
+	return i, err
+}
@@ -0,0 +1,18 @@
+package database
+
+import "context"
+
+// RunInTx runs fn with a Queries that is bound to a new transaction. the transaction is committed if fn
+// returns nil, and rolled back otherwise
+func (s *State) RunInTx(ctx context.Context, fn func(queries *Queries) error) (err error) {
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	fnErr := fn(s.Db.WithTx(tx))
+	if fnErr != nil {
+		tx.Rollback()
+		return fnErr
+	}
+	return tx.Commit()
+}
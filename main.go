@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 
 	"github.com/sohWenMing/aggregator/commands"
@@ -20,7 +21,8 @@ func main() {
 	if err != nil {
 		os.Exit(1)
 	}
-	execCommandErr := commandsPtr.ExecCommand(cmd, writer, state)
+	ctx := context.Background()
+	execCommandErr := commandsPtr.ExecCommand(ctx, cmd, writer, state)
 	if execCommandErr != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,115 @@
+package rss_parsing
+
+import (
+	"html"
+	"strings"
+	"time"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+// filled in by decodeAtomEntry rather than by struct tags
+type atomEntry struct {
+	Title     atomText
+	ID        string
+	Links     []atomLink
+	Summary   atomText
+	Content   atomText
+	Updated   string
+	Published string
+
+	MediaContents []mediaContent
+	MediaGroups   []mediaGroup
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// atom text constructs can either be plain text, escaped html, or inline xhtml. for xhtml the markup is
+// nested inside the element, so the inner xml has to be kept instead of only the character data
+type atomText struct {
+	Type     string `xml:"type,attr"`
+	CharData string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+func (a atomText) String() string {
+	if a.Type == "xhtml" {
+		return strings.TrimSpace(a.InnerXML)
+	}
+	return strings.TrimSpace(a.CharData)
+}
+
+// the text for fields like titles that are kept as plain text. only html has entities left in it once it has
+// been decoded, plain text is already unescaped by the xml decoder
+func (a atomText) plainText() string {
+	if a.Type == "html" {
+		return html.UnescapeString(a.String())
+	}
+	return a.String()
+}
+
+func atomEntryToItem(entry atomEntry) (item RSSItem) {
+	item.Title = entry.Title.plainText()
+	item.GUID = entry.ID
+
+	item.Link = getAlternateLink(entry.Links)
+	if item.Link == "" && isHttpURL(entry.ID) {
+		//entries without an alternate link very often use the permalink as the id
+		item.Link = strings.TrimSpace(entry.ID)
+	}
+
+	item.Content = entry.Content.String()
+	item.Description = entry.Summary.String()
+	if item.Description == "" {
+		item.Description = item.Content
+	}
+
+	pubDate := entry.Published
+	if strings.TrimSpace(pubDate) == "" {
+		pubDate = entry.Updated
+	}
+	item.PubDate = rfc3339ToRSSDate(pubDate)
+
+	for _, link := range entry.Links {
+		if link.Rel == "enclosure" {
+			item.Enclosures = addEnclosure(item.Enclosures, Enclosure{
+				URL:      strings.TrimSpace(link.Href),
+				MimeType: strings.TrimSpace(link.Type),
+				Length:   parseLength(link.Length),
+			})
+		}
+	}
+	item.Enclosures = addMediaContents(item.Enclosures, entry.MediaContents, entry.MediaGroups)
+	return item
+}
+
+// per the atom spec, a link with no rel attribute is treated the same as rel="alternate"
+func getAlternateLink(links []atomLink) (link string) {
+	for _, atomLink := range links {
+		if atomLink.Rel == "" || atomLink.Rel == "alternate" {
+			return strings.TrimSpace(atomLink.Href)
+		}
+	}
+	return ""
+}
+
+// atom and json feed dates are RFC3339, rewritten into the RFC1123Z layout that pubDate in an rss feed uses.
+// if the date can't be parsed it's passed through as is
+func rfc3339ToRSSDate(date string) (rssDate string) {
+	trimmedDate := strings.TrimSpace(date)
+	parsedDate, err := time.Parse(time.RFC3339, trimmedDate)
+	if err != nil {
+		return trimmedDate
+	}
+	return parsedDate.Format(time.RFC1123Z)
+}
+
+func isHttpURL(input string) bool {
+	trimmedInput := strings.TrimSpace(input)
+	return strings.HasPrefix(trimmedInput, "http://") || strings.HasPrefix(trimmedInput, "https://")
+}
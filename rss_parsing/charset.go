@@ -0,0 +1,38 @@
+package rss_parsing
+
+import (
+	"encoding/xml"
+	"io"
+	"mime"
+
+	"golang.org/x/net/html/charset"
+)
+
+// newDecoder returns an xml decoder that converts r to utf-8. a charset in the content type header is used over
+// the encoding in the xml declaration, because servers that re-encode feeds don't rewrite the declaration.
+// if the header has no charset, or one that isn't recognised, the declaration decides
+func newDecoder(r io.Reader, contentType string) *xml.Decoder {
+	label := getContentTypeCharset(contentType)
+	if label != "" {
+		utf8Reader, err := charset.NewReaderLabel(label, r)
+		if err == nil {
+			decoder := xml.NewDecoder(utf8Reader)
+			//the document has already been converted, so the encoding it declares no longer applies
+			decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+				return input, nil
+			}
+			return decoder
+		}
+	}
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	return decoder
+}
+
+func getContentTypeCharset(contentType string) (label string) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return params["charset"]
+}
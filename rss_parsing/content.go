@@ -0,0 +1,29 @@
+package rss_parsing
+
+import (
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+const (
+	ContentPolicyStrict = "strict"
+	ContentPolicyUGC    = "ugc"
+)
+
+// policies are only built the first time they're used, and are safe to share between goroutines once built
+var contentPolicies = map[string]func() *bluemonday.Policy{
+	ContentPolicyStrict: sync.OnceValue(bluemonday.StrictPolicy),
+	ContentPolicyUGC:    sync.OnceValue(bluemonday.UGCPolicy),
+}
+
+// SanitizeContent sanitizes the full content of an item with the named policy. "strict" strips all html, and
+// "ugc" keeps formatting, links and images while removing scripts, styles and event handlers. unknown policy
+// names are treated as strict
+func SanitizeContent(content string, policyName string) string {
+	getPolicy, ok := contentPolicies[policyName]
+	if !ok {
+		getPolicy = contentPolicies[ContentPolicyStrict]
+	}
+	return getPolicy().Sanitize(content)
+}
@@ -0,0 +1,144 @@
+package rss_parsing
+
+import (
+	"html"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	itunesNamespace   = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	mediaRSSNamespace = "http://search.yahoo.com/mrss/"
+	contentNamespace  = "http://purl.org/rss/1.0/modules/content/"
+)
+
+// Enclosure is a media file attached to an item, such as the audio of a podcast episode. Length and Duration
+// are zero when the feed doesn't give them
+type Enclosure struct {
+	URL      string
+	MimeType string
+	Length   int64
+	Duration time.Duration
+}
+
+// an rss item with the elements that enclosures are built from, which are collected into RSSItem.Enclosures.
+// it's filled in by decodeRSSItem rather than by struct tags
+type rssItem struct {
+	RSSItem
+	RSSEnclosures  []rssEnclosure
+	MediaContents  []mediaContent
+	MediaGroups    []mediaGroup
+	ITunesDuration string
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type mediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+type mediaGroup struct {
+	Contents []mediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+}
+
+func rssItemToItem(parsedItem rssItem) (item RSSItem) {
+	item = parsedItem.RSSItem
+	item.Title = html.UnescapeString(item.Title)
+	for _, enclosure := range parsedItem.RSSEnclosures {
+		item.Enclosures = addEnclosure(item.Enclosures, Enclosure{
+			URL:      strings.TrimSpace(enclosure.URL),
+			MimeType: strings.TrimSpace(enclosure.Type),
+			Length:   parseLength(enclosure.Length),
+		})
+	}
+	item.Enclosures = addMediaContents(item.Enclosures, parsedItem.MediaContents, parsedItem.MediaGroups)
+
+	//itunes:duration is the length of the episode, which is the first enclosure
+	if len(item.Enclosures) > 0 && item.Enclosures[0].Duration == 0 {
+		item.Enclosures[0].Duration = parseITunesDuration(parsedItem.ITunesDuration)
+	}
+	return item
+}
+
+func addMediaContents(enclosures []Enclosure, contents []mediaContent, groups []mediaGroup) []Enclosure {
+	for _, group := range groups {
+		contents = append(contents, group.Contents...)
+	}
+	for _, content := range contents {
+		enclosures = addEnclosure(enclosures, Enclosure{
+			URL:      strings.TrimSpace(content.URL),
+			MimeType: strings.TrimSpace(content.Type),
+			Length:   parseLength(content.FileSize),
+			Duration: parseITunesDuration(content.Duration),
+		})
+	}
+	return enclosures
+}
+
+// feeds often give the same file as both an enclosure and media:content, so an enclosure with a url that has
+// already been added only fills in the fields that are still missing
+func addEnclosure(enclosures []Enclosure, enclosure Enclosure) []Enclosure {
+	if enclosure.URL == "" {
+		return enclosures
+	}
+	for i, existing := range enclosures {
+		if existing.URL != enclosure.URL {
+			continue
+		}
+		if existing.MimeType == "" {
+			enclosures[i].MimeType = enclosure.MimeType
+		}
+		if existing.Length == 0 {
+			enclosures[i].Length = enclosure.Length
+		}
+		if existing.Duration == 0 {
+			enclosures[i].Duration = enclosure.Duration
+		}
+		return enclosures
+	}
+	return append(enclosures, enclosure)
+}
+
+func parseLength(input string) (length int64) {
+	parsedLength, err := strconv.ParseInt(strings.TrimSpace(input), 10, 64)
+	if err != nil || parsedLength < 0 {
+		return 0
+	}
+	return parsedLength
+}
+
+// itunes:duration is either a number of seconds, or in the form of MM:SS or HH:MM:SS. the seconds can have a
+// fractional part. zero is returned if the duration can't be parsed
+func parseITunesDuration(input string) (duration time.Duration) {
+	trimmedInput := strings.TrimSpace(input)
+	if trimmedInput == "" {
+		return 0
+	}
+	parts := strings.Split(trimmedInput, ":")
+	if len(parts) > 3 {
+		return 0
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	total := time.Duration(seconds * float64(time.Second))
+	multiplier := time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		value, err := strconv.Atoi(parts[i])
+		if err != nil || value < 0 {
+			return 0
+		}
+		total += time.Duration(value) * multiplier
+		multiplier *= 60
+	}
+	return total
+}
@@ -0,0 +1,121 @@
+package rss_parsing
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"mime"
+	"strings"
+	"time"
+)
+
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Language    string         `json:"language"`
+	Icon        string         `json:"icon"`
+	Favicon     string         `json:"favicon"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string `json:"id"`
+	URL           string `json:"url"`
+	ExternalURL   string `json:"external_url"`
+	Title         string `json:"title"`
+	ContentHTML   string `json:"content_html"`
+	ContentText   string `json:"content_text"`
+	Summary       string `json:"summary"`
+	DatePublished string `json:"date_published"`
+	DateModified  string `json:"date_modified"`
+
+	Attachments []jsonFeedAttachment `json:"attachments"`
+}
+
+type jsonFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+// IsJSONFeed decides whether a response body should be decoded as a JSON Feed, first going by the
+// Content-Type header and then falling back to checking if the body looks like a json object
+func IsJSONFeed(contentType string, buf []byte) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		switch mediaType {
+		case "application/feed+json", "application/json":
+			return true
+		case "application/rss+xml", "application/atom+xml", "application/rdf+xml":
+			return false
+		}
+	}
+	trimmedBuf := bytes.TrimSpace(bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf")))
+	return bytes.HasPrefix(trimmedBuf, []byte("{"))
+}
+
+// ParseJSONFeed decodes a jsonfeed.org version 1 or 1.1 document into the same RSSFeed that ParseRSS returns.
+// relative urls in the items are resolved against the home_page_url, or against source.URL if the feed has no
+// absolute home page
+func ParseJSONFeed(buf []byte, source FeedSource) (rssFeed RSSFeed, err error) {
+	parsedJSONFeed := jsonFeed{}
+	unMarshalErr := json.Unmarshal(buf, &parsedJSONFeed)
+	if unMarshalErr != nil {
+		return RSSFeed{}, unMarshalErr
+	}
+	if !strings.HasPrefix(parsedJSONFeed.Version, jsonFeedVersionPrefix) {
+		return RSSFeed{}, ErrorUnknownFeedFormat
+	}
+
+	returnedFeed := RSSFeed{}
+	//json feed titles are plain text, there is no html in them to unescape
+	returnedFeed.Channel.Title = parsedJSONFeed.Title
+	bases := newBaseURLs(source.URL)
+	returnedFeed.Channel.Link = bases.setChannelLink(parsedJSONFeed.HomePageURL)
+	returnedFeed.Channel.Description = html.UnescapeString(parsedJSONFeed.Description)
+	returnedFeed.Channel.Language = parsedJSONFeed.Language
+	returnedFeed.Channel.ImageURL = firstNonEmpty(parsedJSONFeed.Icon, parsedJSONFeed.Favicon)
+	for _, jsonItem := range parsedJSONFeed.Items {
+		returnedFeed.Channel.RSSItems = append(returnedFeed.Channel.RSSItems, jsonFeedItemToItem(jsonItem))
+	}
+	cleanFeed(&returnedFeed)
+	for i, item := range returnedFeed.Channel.RSSItems {
+		returnedFeed.Channel.RSSItems[i] = resolveItemURLs(item, bases.get())
+	}
+	return returnedFeed, nil
+}
+
+func jsonFeedItemToItem(jsonItem jsonFeedItem) (item RSSItem) {
+	item.Title = jsonItem.Title
+	item.GUID = jsonItem.ID
+	item.Link = firstNonEmpty(jsonItem.URL, jsonItem.ExternalURL)
+	if item.Link == "" && isHttpURL(jsonItem.ID) {
+		item.Link = strings.TrimSpace(jsonItem.ID)
+	}
+	item.Description = firstNonEmpty(jsonItem.Summary, jsonItem.ContentHTML, jsonItem.ContentText)
+	item.Content = firstNonEmpty(jsonItem.ContentHTML, html.EscapeString(jsonItem.ContentText))
+	item.PubDate = rfc3339ToRSSDate(firstNonEmpty(jsonItem.DatePublished, jsonItem.DateModified))
+	for _, attachment := range jsonItem.Attachments {
+		item.Enclosures = addEnclosure(item.Enclosures, Enclosure{
+			URL:      strings.TrimSpace(attachment.URL),
+			MimeType: strings.TrimSpace(attachment.MimeType),
+			Length:   max(attachment.SizeInBytes, 0),
+			Duration: time.Duration(max(attachment.DurationInSeconds, 0) * float64(time.Second)),
+		})
+	}
+	return item
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
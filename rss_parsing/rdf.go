@@ -0,0 +1,53 @@
+package rss_parsing
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	rdfNamespace        = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	rss1Namespace       = "http://purl.org/rss/1.0/"
+	dublinCoreNamespace = "http://purl.org/dc/elements/1.1/"
+)
+
+// in RSS 1.0 the items are siblings of the channel under the rdf:RDF root, rather than children of the channel.
+// the channel and items are filled in by decodeRDFChannel and decodeRDFItem rather than by struct tags
+type rdfChannel struct {
+	Title       string
+	Link        string
+	Description string
+	Language    string
+
+	UpdatePeriod    string
+	UpdateFrequency string
+}
+
+type rdfItem struct {
+	About       string
+	Title       string
+	Link        string
+	Description string
+	Date        string
+	Content     string
+}
+
+func rdfChannelToChannel(channel rdfChannel, feed *RSSFeed) {
+	feed.Channel.Title = html.UnescapeString(channel.Title)
+	feed.Channel.Link = strings.TrimSpace(channel.Link)
+	feed.Channel.Description = html.UnescapeString(channel.Description)
+	feed.Channel.Language = channel.Language
+	feed.Channel.UpdatePeriod = channel.UpdatePeriod
+	feed.Channel.UpdateFrequency = channel.UpdateFrequency
+}
+
+func rdfItemToItem(rdfItem rdfItem) (item RSSItem) {
+	return RSSItem{
+		Title:       html.UnescapeString(rdfItem.Title),
+		Link:        strings.TrimSpace(rdfItem.Link),
+		Description: rdfItem.Description,
+		PubDate:     rfc3339ToRSSDate(rdfItem.Date),
+		GUID:        rdfItem.About,
+		Content:     rdfItem.Content,
+	}
+}
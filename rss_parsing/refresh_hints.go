@@ -0,0 +1,125 @@
+package rss_parsing
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RefreshHints are the publisher's requests for how often the feed should be polled, taken from the rss
+// <ttl>, <skipHours> and <skipDays> elements and the syndication module's sy:updatePeriod and sy:updateFrequency
+type RefreshHints struct {
+	TTL             time.Duration
+	SkipHours       []int
+	SkipDays        []time.Weekday
+	UpdatePeriod    string
+	UpdateFrequency int
+}
+
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// RefreshHints returns the refresh hints declared in the channel of the feed
+func (r RSSFeed) RefreshHints() RefreshHints {
+	return ParseRefreshHints(r.Channel.TTL, r.Channel.SkipHours, r.Channel.SkipDays,
+		r.Channel.UpdatePeriod, r.Channel.UpdateFrequency)
+}
+
+// ParseRefreshHints builds RefreshHints from the raw element values, ignoring any values that are invalid.
+// ttl is in minutes, skipHours are hours from 0 to 23 and skipDays are the english names of the days
+func ParseRefreshHints(ttl string, skipHours, skipDays []string, updatePeriod, updateFrequency string) RefreshHints {
+	hints := RefreshHints{}
+
+	ttlMinutes, err := strconv.Atoi(strings.TrimSpace(ttl))
+	if err == nil && ttlMinutes > 0 {
+		hints.TTL = time.Duration(ttlMinutes) * time.Minute
+	}
+
+	for _, skipHour := range skipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(skipHour))
+		//some publishers use 24 for midnight
+		if err == nil && hour == 24 {
+			hour = 0
+		}
+		if err == nil && hour >= 0 && hour <= 23 {
+			hints.SkipHours = append(hints.SkipHours, hour)
+		}
+	}
+
+	for _, skipDay := range skipDays {
+		weekday, found := weekdays[strings.ToLower(strings.TrimSpace(skipDay))]
+		if found {
+			hints.SkipDays = append(hints.SkipDays, weekday)
+		}
+	}
+
+	period := strings.ToLower(strings.TrimSpace(updatePeriod))
+	if _, found := updatePeriods[period]; found {
+		hints.UpdatePeriod = period
+		hints.UpdateFrequency = 1
+		frequency, err := strconv.Atoi(strings.TrimSpace(updateFrequency))
+		if err == nil && frequency > 0 {
+			hints.UpdateFrequency = frequency
+		}
+	}
+	return hints
+}
+
+// MinInterval is the shortest time the publisher wants between fetches, the larger of the ttl and the
+// interval given by the syndication module
+func (r RefreshHints) MinInterval() time.Duration {
+	minInterval := r.TTL
+	if r.UpdatePeriod != "" {
+		updateInterval := updatePeriods[r.UpdatePeriod] / time.Duration(r.UpdateFrequency)
+		if updateInterval > minInterval {
+			minInterval = updateInterval
+		}
+	}
+	return minInterval
+}
+
+// HasHints reports whether the feed declared any refresh hints at all
+func (r RefreshHints) HasHints() bool {
+	return r.MinInterval() > 0 || len(r.SkipHours) > 0 || len(r.SkipDays) > 0
+}
+
+// NextAllowedFetch works out the earliest time after lastFetched that the feed can be fetched again. skip
+// hours and days are in GMT as per the rss spec, so the check is done against the UTC time. the result is in
+// the same location as lastFetched, as it's stored without a time zone and compared against the local time
+func (r RefreshHints) NextAllowedFetch(lastFetched time.Time) time.Time {
+	nextFetch := lastFetched.Add(r.MinInterval()).UTC()
+	//a week of hours is enough to get out of any combination of skip hours and days, unless every hour is skipped
+	for i := 0; i < 7*24 && r.isSkipped(nextFetch); i++ {
+		nextFetch = nextFetch.Truncate(time.Hour).Add(time.Hour)
+	}
+	return nextFetch.In(lastFetched.Location())
+}
+
+func (r RefreshHints) isSkipped(t time.Time) bool {
+	for _, skipHour := range r.SkipHours {
+		if t.Hour() == skipHour {
+			return true
+		}
+	}
+	for _, skipDay := range r.SkipDays {
+		if t.Weekday() == skipDay {
+			return true
+		}
+	}
+	return false
+}
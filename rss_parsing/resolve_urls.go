@@ -0,0 +1,158 @@
+package rss_parsing
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// the urls that relative links in an item are resolved against, used in order of preference
+type baseURLs struct {
+	// from the xml:base attributes of the item and the elements it's nested in
+	xmlBase     *url.URL
+	channelLink *url.URL
+	feedURL     *url.URL
+}
+
+func newBaseURLs(feedURL string) (bases baseURLs) {
+	bases.feedURL = parseAbsoluteURL(nil, feedURL)
+	return bases
+}
+
+func (b baseURLs) get() *url.URL {
+	switch {
+	case b.xmlBase != nil:
+		return b.xmlBase
+	case b.channelLink != nil:
+		return b.channelLink
+	default:
+		return b.feedURL
+	}
+}
+
+// returns the bases with the xml:base attribute of start applied, if it has one. per the xml base spec a
+// relative xml:base is resolved against the base of its parent element, or the address of the document. the
+// channel link is used if neither is known
+func (b baseURLs) withXMLBase(start xml.StartElement) baseURLs {
+	for _, attr := range start.Attr {
+		if attr.Name.Space != xmlNamespace || attr.Name.Local != "base" {
+			continue
+		}
+		parent := b.documentBase()
+		if parent == nil {
+			parent = b.channelLink
+		}
+		if xmlBase := parseAbsoluteURL(parent, attr.Value); xmlBase != nil {
+			b.xmlBase = xmlBase
+		}
+	}
+	return b
+}
+
+// the base that the feed's own elements are relative to, ignoring the channel link
+func (b baseURLs) documentBase() *url.URL {
+	if b.xmlBase != nil {
+		return b.xmlBase
+	}
+	return b.feedURL
+}
+
+// sets the channel link that items without an xml:base are resolved against, returning the link resolved
+// so it can be stored as an absolute url too
+func (b *baseURLs) setChannelLink(link string) (resolvedLink string) {
+	channelLink := parseAbsoluteURL(b.documentBase(), link)
+	if channelLink == nil {
+		return strings.TrimSpace(link)
+	}
+	b.channelLink = channelLink
+	return channelLink.String()
+}
+
+// parses input as a url, resolved against base if it's relative. nil is returned if the result still isn't absolute
+func parseAbsoluteURL(base *url.URL, input string) *url.URL {
+	trimmedInput := strings.TrimSpace(input)
+	if trimmedInput == "" {
+		return nil
+	}
+	parsedURL, err := url.Parse(trimmedInput)
+	if err != nil {
+		return nil
+	}
+	if base != nil {
+		parsedURL = base.ResolveReference(parsedURL)
+	}
+	if !parsedURL.IsAbs() {
+		return nil
+	}
+	return parsedURL
+}
+
+// resolves input against base, leaving it as it is if it can't be parsed or there is no base
+func resolveURL(base *url.URL, input string) string {
+	if base == nil {
+		return input
+	}
+	resolvedURL := parseAbsoluteURL(base, input)
+	if resolvedURL == nil {
+		return input
+	}
+	return resolvedURL.String()
+}
+
+func resolveItemURLs(item RSSItem, base *url.URL) RSSItem {
+	if base == nil {
+		return item
+	}
+	item.Link = resolveURL(base, item.Link)
+	for i, enclosure := range item.Enclosures {
+		item.Enclosures[i].URL = resolveURL(base, enclosure.URL)
+	}
+	item.Content = resolveContentURLs(item.Content, base)
+	return item
+}
+
+// rewrites the href and src attributes in an html fragment to be absolute. the rest of the html is passed
+// through as it is
+func resolveContentURLs(content string, base *url.URL) string {
+	if !strings.Contains(content, "href") && !strings.Contains(content, "src") {
+		return content
+	}
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	output := strings.Builder{}
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() == io.EOF {
+				return output.String()
+			}
+			return content
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			output.Write(tokenizer.Raw())
+			continue
+		}
+		raw := string(tokenizer.Raw())
+		token := tokenizer.Token()
+		isChanged := false
+		for i, attr := range token.Attr {
+			if attr.Namespace != "" || (attr.Key != "href" && attr.Key != "src") {
+				continue
+			}
+			resolved := resolveURL(base, attr.Val)
+			if resolved != attr.Val {
+				token.Attr[i].Val = resolved
+				isChanged = true
+			}
+		}
+		if !isChanged {
+			output.WriteString(raw)
+			continue
+		}
+		output.WriteString(token.String())
+	}
+}
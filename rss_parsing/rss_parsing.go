@@ -1,18 +1,31 @@
 package rss_parsing
 
 import (
-	"encoding/xml"
+	"errors"
 	"html"
+	"strings"
 
 	"github.com/microcosm-cc/bluemonday"
 )
 
+var ErrorUnknownFeedFormat = errors.New("document is not a recognised feed format")
+
 type RSSFeed struct {
 	Channel struct {
 		Title       string    `xml:"title"`
 		Link        string    `xml:"link"`
 		Description string    `xml:"description"`
 		RSSItems    []RSSItem `xml:"item"`
+
+		Language  string `xml:"language"`
+		ImageURL  string `xml:"image>url"`
+		Generator string `xml:"generator"`
+
+		TTL             string   `xml:"ttl"`
+		SkipHours       []string `xml:"skipHours>hour"`
+		SkipDays        []string `xml:"skipDays>day"`
+		UpdatePeriod    string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
 	} `xml:"channel"`
 }
 
@@ -21,22 +34,38 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
-}
+	GUID        string `xml:"guid"`
 
-func ParseRSS(buf []byte) (rssFeed RSSFeed, err error) {
+	// Content is the html of the item, the full article when the feed has one separately from the description,
+	// or else the description before its html was stripped. it's raw html from the feed, and has to be
+	// sanitized with SanitizeContent before it's stored or shown
+	Content    string      `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Enclosures []Enclosure `xml:"-"`
+}
 
-	htmlParser := bluemonday.StrictPolicy()
+var strictPolicy = bluemonday.StrictPolicy()
 
-	returnedFeed := RSSFeed{}
-	unMarshalErr := xml.Unmarshal(buf, &returnedFeed)
-	if unMarshalErr != nil {
-		return RSSFeed{}, unMarshalErr
+// strips html from the text fields of the feed, applied to every feed format after it has been mapped. titles
+// are unescaped when each format is mapped, as only some formats escape them
+func cleanFeed(feed *RSSFeed) {
+	for i, item := range feed.Channel.RSSItems {
+		feed.Channel.RSSItems[i] = cleanItem(item)
 	}
-	for i, item := range returnedFeed.Channel.RSSItems {
-		returnedFeed.Channel.RSSItems[i].Title = html.UnescapeString(item.Title)
-		returnedFeed.Channel.RSSItems[i].Description = htmlParser.Sanitize(html.UnescapeString(item.Description))
+	cleanChannel(feed)
+}
+
+func cleanItem(item RSSItem) RSSItem {
+	item.GUID = strings.TrimSpace(item.GUID)
+	item.Content = strings.TrimSpace(item.Content)
+	if item.Content == "" {
+		item.Content = strings.TrimSpace(html.UnescapeString(item.Description))
 	}
-	returnedFeed.Channel.Title = html.UnescapeString(returnedFeed.Channel.Title)
-	returnedFeed.Channel.Description = html.UnescapeString(returnedFeed.Channel.Description)
-	return returnedFeed, nil
+	item.Description = strictPolicy.Sanitize(html.UnescapeString(item.Description))
+	return item
+}
+
+func cleanChannel(feed *RSSFeed) {
+	feed.Channel.Language = strings.TrimSpace(feed.Channel.Language)
+	feed.Channel.ImageURL = strings.TrimSpace(feed.Channel.ImageURL)
+	feed.Channel.Generator = strings.TrimSpace(html.UnescapeString(feed.Channel.Generator))
 }
@@ -1,20 +1,24 @@
 package rss_parsing
 
 import (
+	"bytes"
+	"errors"
 	"io"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	testutils "github.com/sohWenMing/aggregator/test_utils"
 )
 
 func TestParseRSS(t *testing.T) {
-	buf := getXMLBuf(t)
+	buf := getXMLBuf(t, "testfile.xml")
 	rssFeed, err := ParseRSS(buf)
 	testutils.AssertNoErr(err, t)
 	testutils.AssertStrings(rssFeed.Channel.Title, "Lane's Blog", t)
 	testutils.AssertStrings(rssFeed.Channel.Description, "Recent content on Lane's Blog", t)
+	testutils.AssertStrings(rssFeed.Channel.RSSItems[0].GUID, "https://wagslane.dev/posts/zen-of-proverbs/", t)
 	for _, item := range rssFeed.Channel.RSSItems {
 		if strings.Contains(item.Description, "&amp") {
 			t.Errorf("unescaped description: %s\n", item.Description)
@@ -25,8 +29,590 @@ func TestParseRSS(t *testing.T) {
 	}
 }
 
-func getXMLBuf(t *testing.T) (buf []byte) {
-	testFile, err := os.Open("testfile.xml")
+func TestParseAtom(t *testing.T) {
+	buf := getXMLBuf(t, "testfile_atom.xml")
+	rssFeed, err := ParseRSS(buf)
+	testutils.AssertNoErr(err, t)
+	testutils.AssertStrings(rssFeed.Channel.Title, "Example Atom Blog", t)
+	testutils.AssertStrings(rssFeed.Channel.Description, "Posts about Go & databases", t)
+	testutils.AssertStrings(rssFeed.Channel.Link, "https://example.org/", t)
+	if len(rssFeed.Channel.RSSItems) != 3 {
+		t.Fatalf("got %d items, want 3", len(rssFeed.Channel.RSSItems))
+	}
+
+	type testStruct struct {
+		name                string
+		expectedTitle       string
+		expectedLink        string
+		expectedDescription string
+		expectedPubDate     string
+		expectedGUID        string
+	}
+	tests := []testStruct{
+		{
+			"entry with summary and published date",
+			`Writing "better" SQL`,
+			"https://example.org/posts/better-sql/",
+			"A short look at joins.",
+			"Fri, 20 Dec 2024 18:30:02 +0000",
+			"tag:example.org,2024:better-sql",
+		},
+		{
+			"entry with xhtml content and updated date",
+			"Inline xhtml content",
+			"https://example.org/posts/xhtml/",
+			"Only content, no summary.",
+			"Sat, 02 Nov 2024 10:15:00 +0800",
+			"https://example.org/posts/xhtml/",
+		},
+		{
+			"entry with link only in id",
+			"Link only in the id",
+			"https://example.org/posts/id-only/",
+			"Plain text summary",
+			"Tue, 01 Oct 2024 00:00:00 +0000",
+			"https://example.org/posts/id-only/",
+		},
+	}
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := rssFeed.Channel.RSSItems[i]
+			testutils.AssertStrings(item.Title, test.expectedTitle, t)
+			testutils.AssertStrings(item.Link, test.expectedLink, t)
+			testutils.AssertStrings(item.Description, test.expectedDescription, t)
+			testutils.AssertStrings(item.PubDate, test.expectedPubDate, t)
+			testutils.AssertStrings(item.GUID, test.expectedGUID, t)
+		})
+	}
+}
+
+func TestParseAtomTextTitles(t *testing.T) {
+	// a text title is already unescaped by the decoder, so entities that are left are part of the text itself
+	rssFeed, err := ParseRSS([]byte(`<feed xmlns="http://www.w3.org/2005/Atom">` +
+		`<title type="text">Escaping &amp;lt; in HTML</title>` +
+		`<entry><title>Why &amp;amp; is needed</title></entry>` +
+		`<entry><title type="html">Writing &amp;lt;b&amp;gt; tags</title></entry></feed>`))
+	testutils.AssertNoErr(err, t)
+	testutils.AssertStrings(rssFeed.Channel.Title, "Escaping &lt; in HTML", t)
+	testutils.AssertStrings(rssFeed.Channel.RSSItems[0].Title, "Why &amp; is needed", t)
+	testutils.AssertStrings(rssFeed.Channel.RSSItems[1].Title, "Writing <b> tags", t)
+}
+
+func TestParseRDF(t *testing.T) {
+	buf := getXMLBuf(t, "testfile_rdf.xml")
+	rssFeed, err := ParseRSS(buf)
+	testutils.AssertNoErr(err, t)
+	testutils.AssertStrings(rssFeed.Channel.Title, "Example Agency News", t)
+	testutils.AssertStrings(rssFeed.Channel.Link, "https://example.gov/news/", t)
+	if len(rssFeed.Channel.RSSItems) != 2 {
+		t.Fatalf("got %d items, want 2", len(rssFeed.Channel.RSSItems))
+	}
+	first := rssFeed.Channel.RSSItems[0]
+	testutils.AssertStrings(first.Title, "Budget & spending update", t)
+	testutils.AssertStrings(first.Link, "https://example.gov/news/2024/12/budget", t)
+	testutils.AssertStrings(first.PubDate, "Fri, 20 Dec 2024 18:30:02 +0000", t)
+	testutils.AssertStrings(first.GUID, "https://example.gov/news/2024/12/budget", t)
+	second := rssFeed.Channel.RSSItems[1]
+	testutils.AssertStrings(second.Description, "The annual report is now available.", t)
+	testutils.AssertStrings(second.PubDate, "Sat, 02 Nov 2024 10:15:00 +0800", t)
+}
+
+func TestRefreshHints(t *testing.T) {
+	buf := getXMLBuf(t, "testfile_hints.xml")
+	rssFeed, err := ParseRSS(buf)
+	testutils.AssertNoErr(err, t)
+	hints := rssFeed.RefreshHints()
+	if hints.TTL != time.Hour {
+		t.Errorf("got ttl: %v\nwant: %v\n", hints.TTL, time.Hour)
+	}
+	testutils.AssertInts(len(hints.SkipHours), 3, t)
+	testutils.AssertInts(len(hints.SkipDays), 1, t)
+	testutils.AssertStrings(hints.UpdatePeriod, "daily", t)
+	testutils.AssertInts(hints.UpdateFrequency, 4, t)
+	if hints.MinInterval() != 6*time.Hour {
+		t.Errorf("got min interval: %v\nwant: %v\n", hints.MinInterval(), 6*time.Hour)
+	}
+
+	type testStruct struct {
+		name        string
+		lastFetched time.Time
+		expected    time.Time
+	}
+	tests := []testStruct{
+		{
+			"next fetch not in a skipped hour",
+			time.Date(2025, 1, 8, 6, 30, 0, 0, time.UTC),
+			time.Date(2025, 1, 8, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			"next fetch pushed past skipped hours",
+			time.Date(2025, 1, 8, 18, 30, 0, 0, time.UTC),
+			time.Date(2025, 1, 9, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			"next fetch pushed past skipped day",
+			time.Date(2025, 1, 11, 20, 0, 0, 0, time.UTC),
+			time.Date(2025, 1, 12, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			// skip hours are in GMT, but the result stays in the zone it was given in
+			"next fetch in another zone",
+			time.Date(2025, 1, 9, 2, 30, 0, 0, time.FixedZone("SGT", 8*60*60)),
+			time.Date(2025, 1, 9, 11, 0, 0, 0, time.FixedZone("SGT", 8*60*60)),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := hints.NextAllowedFetch(test.lastFetched)
+			if !got.Equal(test.expected) {
+				t.Errorf("got: %v\nwant: %v\n", got, test.expected)
+			}
+			if got.Location() != test.lastFetched.Location() {
+				t.Errorf("got location: %v\nwant: %v\n", got.Location(), test.lastFetched.Location())
+			}
+		})
+	}
+}
+
+func TestParseJSONFeed(t *testing.T) {
+	buf := getXMLBuf(t, "testfile.json")
+	rssFeed, err := ParseJSONFeed(buf, FeedSource{})
+	testutils.AssertNoErr(err, t)
+	testutils.AssertStrings(rssFeed.Channel.Title, "Example JSON Blog", t)
+	testutils.AssertStrings(rssFeed.Channel.Link, "https://example.org/", t)
+	testutils.AssertStrings(rssFeed.Channel.Description, "Notes & experiments", t)
+	if len(rssFeed.Channel.RSSItems) != 2 {
+		t.Fatalf("got %d items, want 2", len(rssFeed.Channel.RSSItems))
+	}
+	first := rssFeed.Channel.RSSItems[0]
+	// titles are plain text, so an entity in one is kept as it is
+	testutils.AssertStrings(first.Title, "Second post: Q&amp;A", t)
+	testutils.AssertStrings(first.Link, "https://example.org/posts/second/", t)
+	testutils.AssertStrings(first.Description, "Hello, world!", t)
+	testutils.AssertStrings(first.PubDate, "Fri, 20 Dec 2024 18:30:02 +0000", t)
+	second := rssFeed.Channel.RSSItems[1]
+	testutils.AssertStrings(second.Link, "https://example.org/posts/first/", t)
+	testutils.AssertStrings(second.Description, "A summary wins over content", t)
+	testutils.AssertStrings(second.PubDate, "Sat, 02 Nov 2024 10:15:00 +0800", t)
+
+	_, err = ParseJSONFeed([]byte(`{"title": "not a json feed"}`), FeedSource{})
+	testutils.AssertHasErr(err, t)
+}
+
+func TestIsJSONFeed(t *testing.T) {
+	type testStruct struct {
+		name        string
+		contentType string
+		body        string
+		expected    bool
+	}
+	tests := []testStruct{
+		{"feed+json content type", "application/feed+json; charset=utf-8", "", true},
+		{"rss content type with json body", "application/rss+xml", "{}", false},
+		{"generic content type sniffs json", "text/plain", "  {\"version\": \"\"}", true},
+		{"generic content type sniffs xml", "text/xml", "<?xml version=\"1.0\"?><rss></rss>", false},
+		{"missing content type", "", "\n<rss></rss>", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := IsJSONFeed(test.contentType, []byte(test.body))
+			if got != test.expected {
+				t.Errorf("got: %v\nwant: %v\n", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	_, err := ParseRSS([]byte(`<?xml version="1.0"?><html><body>not a feed</body></html>`))
+	testutils.AssertHasErr(err, t)
+}
+
+func TestStreamFeed(t *testing.T) {
+	type testStruct struct {
+		name             string
+		fileName         string
+		expectedTitle    string
+		expectedNumItems int
+	}
+	tests := []testStruct{
+		{"rss", "testfile.xml", "Lane's Blog", 21},
+		{"atom", "testfile_atom.xml", "Example Atom Blog", 3},
+		{"rdf", "testfile_rdf.xml", "Example Agency News", 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testFile, err := os.Open(test.fileName)
+			testutils.AssertNoErr(err, t)
+			defer testFile.Close()
+
+			numItems := 0
+			rssFeed, err := StreamFeed(testFile, FeedSource{}, func(item RSSItem) error {
+				numItems++
+				return nil
+			})
+			testutils.AssertNoErr(err, t)
+			testutils.AssertStrings(rssFeed.Channel.Title, test.expectedTitle, t)
+			testutils.AssertInts(numItems, test.expectedNumItems, t)
+			testutils.AssertInts(len(rssFeed.Channel.RSSItems), 0, t)
+		})
+	}
+}
+
+func TestStreamFeedStopsOnItemErr(t *testing.T) {
+	stopErr := errors.New("stop")
+	numItems := 0
+	_, err := StreamFeed(strings.NewReader(string(getXMLBuf(t, "testfile.xml"))), FeedSource{}, func(item RSSItem) error {
+		numItems++
+		return stopErr
+	})
+	if !errors.Is(err, stopErr) {
+		t.Errorf("got: %v\nwant: %v\n", err, stopErr)
+	}
+	testutils.AssertInts(numItems, 1, t)
+}
+
+func TestStreamFeedTruncated(t *testing.T) {
+	buf := getXMLBuf(t, "testfile.xml")
+	_, err := StreamFeed(strings.NewReader(string(buf[:len(buf)/2])), FeedSource{}, func(item RSSItem) error { return nil })
+	testutils.AssertHasErr(err, t)
+}
+
+func TestParseRSSIgnoresAtomLink(t *testing.T) {
+	// atom:link is commonly put in rss channels to point at the feed itself, it shouldn't replace the site link
+	rssFeed, err := ParseRSS([]byte(`<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel><link>https://example.org/</link>` +
+		`<atom:link href="https://example.org/index.xml" rel="self"/></channel></rss>`))
+	testutils.AssertNoErr(err, t)
+	testutils.AssertStrings(rssFeed.Channel.Link, "https://example.org/", t)
+}
+
+func TestParseRSSIgnoresNamespacedItemElements(t *testing.T) {
+	// itunes:title and atom:link share local names with rss elements of the item, they shouldn't replace them
+	buf := getXMLBuf(t, "testfile_podcast.xml")
+	rssFeed, err := ParseRSS(buf)
+	testutils.AssertNoErr(err, t)
+	item := rssFeed.Channel.RSSItems[0]
+	testutils.AssertStrings(item.Title, "Episode 2: Generics", t)
+	testutils.AssertStrings(item.Link, "https://podcast.example.com/episodes/2/", t)
+}
+
+func TestParseAtomIgnoresNamespacedEntryElements(t *testing.T) {
+	// media:title and media:content share local names with atom elements of the entry, they shouldn't replace them
+	rssFeed, err := ParseRSS([]byte(`<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">` +
+		`<entry><title>Entry title</title>` +
+		`<content type="html">&lt;p&gt;Show notes&lt;/p&gt;</content>` +
+		`<media:content url="https://cdn.example.com/a.mp3" type="audio/mpeg"/>` +
+		`<media:title>Media title</media:title></entry></feed>`))
+	testutils.AssertNoErr(err, t)
+	item := rssFeed.Channel.RSSItems[0]
+	testutils.AssertStrings(item.Title, "Entry title", t)
+	testutils.AssertStrings(item.Content, "<p>Show notes</p>", t)
+	if len(item.Enclosures) != 1 {
+		t.Fatalf("got %d enclosures, want 1", len(item.Enclosures))
+	}
+	testutils.AssertStrings(item.Enclosures[0].URL, "https://cdn.example.com/a.mp3", t)
+}
+
+func TestParseRDFIgnoresNamespacedItemElements(t *testing.T) {
+	rssFeed, err := ParseRSS([]byte(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" ` +
+		`xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">` +
+		`<channel><title>Channel title</title><dc:title>Dublin Core channel title</dc:title></channel>` +
+		`<item rdf:about="https://example.gov/news/1"><title>Item title</title><dc:title>Dublin Core title</dc:title>` +
+		`<link>https://example.gov/news/1</link><dc:description>Dublin Core description</dc:description>` +
+		`<description>Item description</description></item></rdf:RDF>`))
+	testutils.AssertNoErr(err, t)
+	testutils.AssertStrings(rssFeed.Channel.Title, "Channel title", t)
+	item := rssFeed.Channel.RSSItems[0]
+	testutils.AssertStrings(item.Title, "Item title", t)
+	testutils.AssertStrings(item.Link, "https://example.gov/news/1", t)
+	testutils.AssertStrings(item.Description, "Item description", t)
+}
+
+func TestParseCharsets(t *testing.T) {
+	type testStruct struct {
+		name                string
+		buf                 []byte
+		contentType         string
+		expectedTitle       string
+		expectedDescription string
+	}
+	tests := []testStruct{
+		{"iso-8859-1 declaration", getXMLBuf(t, "testfile_latin1.xml"), "", "Café crème", "Nouvelles de la région"},
+		{"windows-1252 declaration", getXMLBuf(t, "testfile_cp1252.xml"), "application/rss+xml", "“Quoted” headlines", "Prices in €"},
+		{"shift_jis declaration", getXMLBuf(t, "testfile_sjis.xml"), "", "日本のニュース", "地域のニュース"},
+		{
+			// the header wins over the declaration, for servers that re-encode feeds without rewriting them
+			"content type charset",
+			[]byte(strings.Replace(string(getXMLBuf(t, "testfile_latin1.xml")), "ISO-8859-1", "UTF-8", 1)),
+			"application/rss+xml; charset=ISO-8859-1",
+			"Café crème",
+			"Nouvelles de la région",
+		},
+		{"unknown content type charset", getXMLBuf(t, "testfile_latin1.xml"), "text/xml; charset=not-a-charset", "Café crème", "Nouvelles de la région"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rssFeed, err := StreamFeed(bytes.NewReader(test.buf), FeedSource{ContentType: test.contentType}, func(item RSSItem) error {
+				testutils.AssertStrings(item.Title, test.expectedTitle, t)
+				testutils.AssertStrings(item.Description, test.expectedDescription, t)
+				return nil
+			})
+			testutils.AssertNoErr(err, t)
+			testutils.AssertStrings(rssFeed.Channel.Title, test.expectedTitle, t)
+			testutils.AssertStrings(rssFeed.Channel.Description, test.expectedDescription, t)
+		})
+	}
+}
+
+func TestParseChannelMetadata(t *testing.T) {
+	type testStruct struct {
+		name              string
+		fileName          string
+		expectedLanguage  string
+		expectedImageURL  string
+		expectedGenerator string
+	}
+	tests := []testStruct{
+		{"rss", "testfile.xml", "en-us", "", "Hugo -- gohugo.io"},
+		// the logo is preferred over the icon, and is relative to the feed's url
+		{"atom", "testfile_atom.xml", "en-GB", "https://example.org/images/logo.png", "Jekyll"},
+		{"rdf", "testfile_rdf.xml", "en-us", "https://example.gov/images/seal.png", ""},
+		{"itunes image", "testfile_podcast.xml", "", "https://cdn.example.com/artwork.jpg", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := getXMLBuf(t, test.fileName)
+			rssFeed, err := StreamFeed(bytes.NewReader(buf), FeedSource{URL: "https://example.org/atom.xml"}, func(item RSSItem) error {
+				return nil
+			})
+			testutils.AssertNoErr(err, t)
+			testutils.AssertStrings(rssFeed.Channel.Language, test.expectedLanguage, t)
+			testutils.AssertStrings(rssFeed.Channel.ImageURL, test.expectedImageURL, t)
+			testutils.AssertStrings(rssFeed.Channel.Generator, test.expectedGenerator, t)
+		})
+	}
+
+	t.Run("json feed", func(t *testing.T) {
+		buf := getXMLBuf(t, "testfile.json")
+		rssFeed, err := ParseJSONFeed(buf, FeedSource{})
+		testutils.AssertNoErr(err, t)
+		testutils.AssertStrings(rssFeed.Channel.Language, "en", t)
+		testutils.AssertStrings(rssFeed.Channel.ImageURL, "https://example.org/icon-512.png", t)
+	})
+}
+
+func TestParseEnclosures(t *testing.T) {
+	buf := getXMLBuf(t, "testfile_podcast.xml")
+	rssFeed, err := ParseRSS(buf)
+	testutils.AssertNoErr(err, t)
+	if len(rssFeed.Channel.RSSItems) != 3 {
+		t.Fatalf("got %d items, want 3", len(rssFeed.Channel.RSSItems))
+	}
+
+	type testStruct struct {
+		name               string
+		item               RSSItem
+		expectedEnclosures []Enclosure
+	}
+	tests := []testStruct{
+		{
+			// the media:content duplicates the enclosure, so it only adds to it rather than being listed twice
+			"enclosure with itunes duration and media content",
+			rssFeed.Channel.RSSItems[0],
+			[]Enclosure{
+				{"https://cdn.example.com/episode-2.mp3", "audio/mpeg", 24986239, time.Hour + 2*time.Minute + 3*time.Second},
+			},
+		},
+		{
+			"media group",
+			rssFeed.Channel.RSSItems[1],
+			[]Enclosure{
+				{"https://cdn.example.com/episode-1.mp3", "audio/mpeg", 0, 30 * time.Minute},
+				{"https://cdn.example.com/episode-1.ogg", "audio/ogg", 0, 0},
+			},
+		},
+		{"no enclosures", rssFeed.Channel.RSSItems[2], nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testutils.AssertInts(len(test.item.Enclosures), len(test.expectedEnclosures), t)
+			for i, expected := range test.expectedEnclosures {
+				if i >= len(test.item.Enclosures) {
+					break
+				}
+				if test.item.Enclosures[i] != expected {
+					t.Errorf("got: %+v\nwant: %+v\n", test.item.Enclosures[i], expected)
+				}
+			}
+		})
+	}
+}
+
+func TestParseITunesDuration(t *testing.T) {
+	type testStruct struct {
+		input    string
+		expected time.Duration
+	}
+	tests := []testStruct{
+		{"3723", 3723 * time.Second},
+		{"62:03", 62*time.Minute + 3*time.Second},
+		{"1:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"90.5", 90*time.Second + 500*time.Millisecond},
+		{"", 0},
+		{"1:2:3:4", 0},
+		{"an hour", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got := parseITunesDuration(test.input)
+			if got != test.expected {
+				t.Errorf("got: %v\nwant: %v\n", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseContent(t *testing.T) {
+	rssFeed, err := ParseRSS(getXMLBuf(t, "testfile_content.xml"))
+	testutils.AssertNoErr(err, t)
+	if len(rssFeed.Channel.RSSItems) != 2 {
+		t.Fatalf("got %d items, want 2", len(rssFeed.Channel.RSSItems))
+	}
+	fullItem := rssFeed.Channel.RSSItems[0]
+	testutils.AssertStrings(fullItem.Description, "The old parser read everything into memory...", t)
+	if !strings.HasPrefix(fullItem.Content, "<p>The old parser read <em>everything</em> into memory.</p>") {
+		t.Errorf("content was not captured: %s\n", fullItem.Content)
+	}
+	// without content:encoded the html of the description is kept as the content
+	testutils.AssertStrings(rssFeed.Channel.RSSItems[1].Content, "No full content for this one.", t)
+
+	atomFeed, err := ParseRSS(getXMLBuf(t, "testfile_atom.xml"))
+	testutils.AssertNoErr(err, t)
+	for _, item := range atomFeed.Channel.RSSItems {
+		if item.Content == "" {
+			t.Errorf("atom entry %s has no content\n", item.Title)
+		}
+	}
+}
+
+func TestSanitizeContent(t *testing.T) {
+	content := `<p>Read <em>this</em></p><script>alert("hi")</script><a href="https://example.com/" onclick="track()">link</a>`
+	type testStruct struct {
+		name       string
+		policyName string
+		expected   string
+	}
+	tests := []testStruct{
+		{"strict", ContentPolicyStrict, "Read thislink"},
+		{"ugc", ContentPolicyUGC, `<p>Read <em>this</em></p><a href="https://example.com/" rel="nofollow">link</a>`},
+		{"unknown policy is strict", "lenient", "Read thislink"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testutils.AssertStrings(SanitizeContent(content, test.policyName), test.expected, t)
+		})
+	}
+}
+
+func TestResolveRelativeURLs(t *testing.T) {
+	source := FeedSource{URL: "https://example.org/feeds/rss.xml"}
+	items := []RSSItem{}
+	rssFeed, err := StreamFeed(bytes.NewReader(getXMLBuf(t, "testfile_relative.xml")), source, func(item RSSItem) error {
+		items = append(items, item)
+		return nil
+	})
+	testutils.AssertNoErr(err, t)
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	// the channel link is itself relative, so it's resolved against the feed url
+	testutils.AssertStrings(rssFeed.Channel.Link, "https://example.org/blog/", t)
+	testutils.AssertStrings(items[0].Link, "https://example.org/blog/posts/first/", t)
+	testutils.AssertStrings(items[0].Content,
+		`<p><a href="https://example.org/about">About</a> <img src="https://example.org/blog/images/chart.png" alt="chart"> <a href="https://other.example.com/">elsewhere</a></p>`, t)
+	testutils.AssertStrings(items[1].Link, "https://cdn.example.org/posts/second/", t)
+	testutils.AssertStrings(items[1].Enclosures[0].URL, "https://cdn.example.org/media/episode-2.mp3", t)
+}
+
+func TestResolveRelativeURLsJSONFeed(t *testing.T) {
+	// the home page is itself relative, so it's resolved against the feed url before the items are resolved against it
+	rssFeed, err := ParseJSONFeed(getXMLBuf(t, "testfile_relative.json"), FeedSource{URL: "https://example.org/feeds/feed.json"})
+	testutils.AssertNoErr(err, t)
+	items := rssFeed.Channel.RSSItems
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2", len(items))
+	}
+	testutils.AssertStrings(rssFeed.Channel.Link, "https://example.org/blog/", t)
+	testutils.AssertStrings(items[0].Link, "https://example.org/blog/posts/first/", t)
+	testutils.AssertStrings(items[0].Content,
+		`<p><a href="https://example.org/about">About</a> <img src="https://example.org/blog/images/chart.png" alt="chart"></p>`, t)
+	testutils.AssertStrings(items[0].Enclosures[0].URL, "https://example.org/media/episode-1.mp3", t)
+	testutils.AssertStrings(items[1].Link, "https://other.example.com/posts/second/", t)
+
+	// without a home page the feed url is used
+	rssFeed, err = ParseJSONFeed([]byte(`{"version": "https://jsonfeed.org/version/1.1", "items": [{"id": "1", "url": "post.html"}]}`),
+		FeedSource{URL: "https://feeds.example.net/blog/feed.json"})
+	testutils.AssertNoErr(err, t)
+	testutils.AssertStrings(rssFeed.Channel.RSSItems[0].Link, "https://feeds.example.net/blog/post.html", t)
+}
+
+func TestResolveRelativeURLsFallbacks(t *testing.T) {
+	type testStruct struct {
+		name         string
+		document     string
+		feedURL      string
+		expectedLink string
+	}
+	tests := []testStruct{
+		{
+			"feed url when there is no channel link",
+			`<rss><channel><item><link>post.html</link></item></channel></rss>`,
+			"https://feeds.example.net/blog/rss.xml",
+			"https://feeds.example.net/blog/post.html",
+		},
+		{
+			"xml:base on the root",
+			`<rss xml:base="https://base.example.com/"><channel><link>https://example.org/</link><item><link>post.html</link></item></channel></rss>`,
+			"https://feeds.example.net/rss.xml",
+			"https://base.example.com/post.html",
+		},
+		{
+			"atom alternate link",
+			`<feed xmlns="http://www.w3.org/2005/Atom"><link href="https://example.org/blog/"/><entry><link href="entry/"/></entry></feed>`,
+			"",
+			"https://example.org/blog/entry/",
+		},
+		{
+			"atom xml:base on the entry",
+			`<feed xmlns="http://www.w3.org/2005/Atom"><link href="https://example.org/"/><entry xml:base="/archive/"><link href="entry/"/></entry></feed>`,
+			"",
+			"https://example.org/archive/entry/",
+		},
+		{
+			"no base at all",
+			`<rss><channel><item><link>post.html</link></item></channel></rss>`,
+			"",
+			"post.html",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			links := []string{}
+			_, err := StreamFeed(strings.NewReader(test.document), FeedSource{URL: test.feedURL}, func(item RSSItem) error {
+				links = append(links, item.Link)
+				return nil
+			})
+			testutils.AssertNoErr(err, t)
+			if len(links) != 1 {
+				t.Fatalf("got %d items, want 1", len(links))
+			}
+			testutils.AssertStrings(links[0], test.expectedLink, t)
+		})
+	}
+}
+
+func getXMLBuf(t *testing.T, fileName string) (buf []byte) {
+	testFile, err := os.Open(fileName)
 	testutils.AssertNoErr(err, t)
 	defer testFile.Close()
 
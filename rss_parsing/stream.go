@@ -0,0 +1,452 @@
+package rss_parsing
+
+import (
+	"bytes"
+	"encoding/xml"
+	"html"
+	"io"
+	"strings"
+)
+
+const syndicationNamespace = "http://purl.org/rss/1.0/modules/syndication/"
+
+// FeedSource describes where a feed document came from. both fields are optional
+type FeedSource struct {
+	// URL is the address the feed was fetched from. relative links in the feed are resolved against it when
+	// the feed has neither an xml:base nor an absolute channel link
+	URL string
+	// ContentType is the Content-Type header the feed was served with. a charset in it is used over the
+	// encoding in the xml declaration
+	ContentType string
+}
+
+// passed the bases that apply to the item, so that its relative urls can be resolved
+type itemHandler func(item RSSItem, bases baseURLs) error
+
+// StreamFeed decodes an rss, atom or rdf document from r one element at a time. each item is cleaned and passed
+// to onItem as soon as it has been decoded, so only one item is held in memory however long the feed is. the
+// returned feed has the channel level fields but no items. if onItem returns an error, decoding stops and the
+// error is returned
+func StreamFeed(r io.Reader, source FeedSource, onItem func(item RSSItem) error) (rssFeed RSSFeed, err error) {
+	decoder := newDecoder(r, source.ContentType)
+	root, err := nextStartElement(decoder)
+	if err != nil {
+		if err == io.EOF {
+			return RSSFeed{}, ErrorUnknownFeedFormat
+		}
+		return RSSFeed{}, err
+	}
+
+	handleItem := func(item RSSItem, bases baseURLs) error {
+		return onItem(resolveItemURLs(cleanItem(item), bases.get()))
+	}
+	bases := newBaseURLs(source.URL).withXMLBase(root)
+
+	returnedFeed := RSSFeed{}
+	switch {
+	case root.Name.Local == "rss":
+		err = streamRSS(decoder, &returnedFeed, bases, handleItem)
+	case root.Name.Local == "feed" && root.Name.Space == atomNamespace:
+		err = streamAtom(decoder, &returnedFeed, bases, handleItem)
+	case root.Name.Local == "RDF" && root.Name.Space == rdfNamespace:
+		err = streamRDF(decoder, &returnedFeed, bases, handleItem)
+	default:
+		return RSSFeed{}, ErrorUnknownFeedFormat
+	}
+	if err != nil {
+		return RSSFeed{}, err
+	}
+	if returnedFeed.Channel.Language == "" {
+		//atom feeds give their language as xml:lang on the root element
+		returnedFeed.Channel.Language = getXMLLang(root)
+	}
+	cleanChannel(&returnedFeed)
+	return returnedFeed, nil
+}
+
+// ParseRSS decodes a whole rss, atom or rdf document that is already in memory, collecting its items into the
+// returned feed. use StreamFeed for documents that could be large
+func ParseRSS(buf []byte) (rssFeed RSSFeed, err error) {
+	var items []RSSItem
+	returnedFeed, err := StreamFeed(bytes.NewReader(buf), FeedSource{}, func(item RSSItem) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return RSSFeed{}, err
+	}
+	returnedFeed.Channel.RSSItems = items
+	return returnedFeed, nil
+}
+
+func streamRSS(decoder *xml.Decoder, feed *RSSFeed, bases baseURLs, onItem itemHandler) error {
+	return forEachChild(decoder, func(start xml.StartElement) error {
+		if start.Name.Local != "channel" {
+			return decoder.Skip()
+		}
+		channelBases := bases.withXMLBase(start)
+		return forEachChild(decoder, func(start xml.StartElement) error {
+			channel := &feed.Channel
+			switch {
+			case start.Name.Local == "item":
+				item, err := decodeRSSItem(decoder)
+				if err != nil {
+					return err
+				}
+				return onItem(rssItemToItem(item), channelBases.withXMLBase(start))
+			case start.Name.Space == syndicationNamespace && start.Name.Local == "updatePeriod":
+				return decoder.DecodeElement(&channel.UpdatePeriod, &start)
+			case start.Name.Space == syndicationNamespace && start.Name.Local == "updateFrequency":
+				return decoder.DecodeElement(&channel.UpdateFrequency, &start)
+			case start.Name.Space == itunesNamespace && start.Name.Local == "image":
+				//podcasts often only have their artwork here, the rss image is used over it when there is one
+				if channel.ImageURL == "" {
+					channel.ImageURL = resolveURL(channelBases.get(), getAttrValue(start, "href"))
+				}
+				return decoder.Skip()
+			case start.Name.Space != "":
+				//extensions like atom:link share local names with rss elements, and would otherwise overwrite them
+				return decoder.Skip()
+			case start.Name.Local == "title":
+				return decodeEscapedText(decoder, start, &channel.Title)
+			case start.Name.Local == "link":
+				if err := decoder.DecodeElement(&channel.Link, &start); err != nil {
+					return err
+				}
+				channel.Link = channelBases.setChannelLink(channel.Link)
+				return nil
+			case start.Name.Local == "description":
+				return decodeEscapedText(decoder, start, &channel.Description)
+			case start.Name.Local == "language":
+				return decoder.DecodeElement(&channel.Language, &start)
+			case start.Name.Local == "generator":
+				return decoder.DecodeElement(&channel.Generator, &start)
+			case start.Name.Local == "image":
+				image := struct {
+					URL string `xml:"url"`
+				}{}
+				if err := decoder.DecodeElement(&image, &start); err != nil {
+					return err
+				}
+				if strings.TrimSpace(image.URL) != "" {
+					channel.ImageURL = resolveURL(channelBases.get(), strings.TrimSpace(image.URL))
+				}
+				return nil
+			case start.Name.Local == "ttl":
+				return decoder.DecodeElement(&channel.TTL, &start)
+			case start.Name.Local == "skipHours":
+				skipHours := struct {
+					Hours []string `xml:"hour"`
+				}{}
+				err := decoder.DecodeElement(&skipHours, &start)
+				channel.SkipHours = append(channel.SkipHours, skipHours.Hours...)
+				return err
+			case start.Name.Local == "skipDays":
+				skipDays := struct {
+					Days []string `xml:"day"`
+				}{}
+				err := decoder.DecodeElement(&skipDays, &start)
+				channel.SkipDays = append(channel.SkipDays, skipDays.Days...)
+				return err
+			}
+			return decoder.Skip()
+		})
+	})
+}
+
+// the children of an item are decoded one at a time, because a struct tag without a namespace matches an element
+// in any namespace, and extensions like itunes:title and atom:link would overwrite the rss elements
+func decodeRSSItem(decoder *xml.Decoder) (item rssItem, err error) {
+	err = forEachChild(decoder, func(start xml.StartElement) error {
+		switch {
+		case start.Name.Space == contentNamespace && start.Name.Local == "encoded":
+			return decoder.DecodeElement(&item.Content, &start)
+		case start.Name.Space == mediaRSSNamespace && start.Name.Local == "content":
+			content := mediaContent{}
+			err := decoder.DecodeElement(&content, &start)
+			item.MediaContents = append(item.MediaContents, content)
+			return err
+		case start.Name.Space == mediaRSSNamespace && start.Name.Local == "group":
+			group := mediaGroup{}
+			err := decoder.DecodeElement(&group, &start)
+			item.MediaGroups = append(item.MediaGroups, group)
+			return err
+		case start.Name.Space == itunesNamespace && start.Name.Local == "duration":
+			return decoder.DecodeElement(&item.ITunesDuration, &start)
+		case start.Name.Space != "":
+			return decoder.Skip()
+		case start.Name.Local == "title":
+			return decoder.DecodeElement(&item.Title, &start)
+		case start.Name.Local == "link":
+			return decoder.DecodeElement(&item.Link, &start)
+		case start.Name.Local == "description":
+			return decoder.DecodeElement(&item.Description, &start)
+		case start.Name.Local == "pubDate":
+			return decoder.DecodeElement(&item.PubDate, &start)
+		case start.Name.Local == "guid":
+			return decoder.DecodeElement(&item.GUID, &start)
+		case start.Name.Local == "enclosure":
+			enclosure := rssEnclosure{}
+			err := decoder.DecodeElement(&enclosure, &start)
+			item.RSSEnclosures = append(item.RSSEnclosures, enclosure)
+			return err
+		}
+		return decoder.Skip()
+	})
+	return item, err
+}
+
+// decoded one child at a time for the same reason as decodeRSSItem, media:title and media:content would
+// otherwise be taken for the entry's title and content
+func decodeAtomEntry(decoder *xml.Decoder) (entry atomEntry, err error) {
+	err = forEachChild(decoder, func(start xml.StartElement) error {
+		switch {
+		case start.Name.Space == mediaRSSNamespace && start.Name.Local == "content":
+			content := mediaContent{}
+			err := decoder.DecodeElement(&content, &start)
+			entry.MediaContents = append(entry.MediaContents, content)
+			return err
+		case start.Name.Space == mediaRSSNamespace && start.Name.Local == "group":
+			group := mediaGroup{}
+			err := decoder.DecodeElement(&group, &start)
+			entry.MediaGroups = append(entry.MediaGroups, group)
+			return err
+		case start.Name.Space != atomNamespace:
+			return decoder.Skip()
+		case start.Name.Local == "title":
+			return decoder.DecodeElement(&entry.Title, &start)
+		case start.Name.Local == "id":
+			return decoder.DecodeElement(&entry.ID, &start)
+		case start.Name.Local == "link":
+			link := atomLink{}
+			err := decoder.DecodeElement(&link, &start)
+			entry.Links = append(entry.Links, link)
+			return err
+		case start.Name.Local == "summary":
+			return decoder.DecodeElement(&entry.Summary, &start)
+		case start.Name.Local == "content":
+			return decoder.DecodeElement(&entry.Content, &start)
+		case start.Name.Local == "updated":
+			return decoder.DecodeElement(&entry.Updated, &start)
+		case start.Name.Local == "published":
+			return decoder.DecodeElement(&entry.Published, &start)
+		}
+		return decoder.Skip()
+	})
+	return entry, err
+}
+
+func streamAtom(decoder *xml.Decoder, feed *RSSFeed, bases baseURLs, onItem itemHandler) error {
+	links := []atomLink{}
+	//the logo is the wider image meant for display, the icon is only used when there is no logo
+	logo, icon := "", ""
+	err := forEachChild(decoder, func(start xml.StartElement) error {
+		channel := &feed.Channel
+		switch {
+		case start.Name.Local == "entry":
+			entry, err := decodeAtomEntry(decoder)
+			if err != nil {
+				return err
+			}
+			return onItem(atomEntryToItem(entry), bases.withXMLBase(start))
+		case start.Name.Space == syndicationNamespace && start.Name.Local == "updatePeriod":
+			return decoder.DecodeElement(&channel.UpdatePeriod, &start)
+		case start.Name.Space == syndicationNamespace && start.Name.Local == "updateFrequency":
+			return decoder.DecodeElement(&channel.UpdateFrequency, &start)
+		case start.Name.Space != atomNamespace:
+			return decoder.Skip()
+		case start.Name.Local == "title":
+			return decodeAtomText(decoder, start, &channel.Title)
+		case start.Name.Local == "subtitle":
+			return decodeAtomText(decoder, start, &channel.Description)
+		case start.Name.Local == "generator":
+			return decoder.DecodeElement(&channel.Generator, &start)
+		case start.Name.Local == "logo":
+			return decoder.DecodeElement(&logo, &start)
+		case start.Name.Local == "icon":
+			return decoder.DecodeElement(&icon, &start)
+		case start.Name.Local == "link":
+			link := atomLink{}
+			if err := decoder.DecodeElement(&link, &start); err != nil {
+				return err
+			}
+			links = append(links, link)
+			//entries usually come after the feed's links, so the alternate link can be used as a base for them
+			if bases.channelLink == nil && getAlternateLink([]atomLink{link}) != "" {
+				bases.setChannelLink(link.Href)
+			}
+			return nil
+		}
+		return decoder.Skip()
+	})
+	feed.Channel.Link = getAlternateLink(links)
+	if bases.channelLink != nil {
+		feed.Channel.Link = bases.channelLink.String()
+	}
+	if strings.TrimSpace(logo) == "" {
+		logo = icon
+	}
+	if strings.TrimSpace(logo) != "" {
+		feed.Channel.ImageURL = resolveURL(bases.documentBase(), strings.TrimSpace(logo))
+	}
+	return err
+}
+
+func decodeAtomText(decoder *xml.Decoder, start xml.StartElement, field *string) error {
+	text := atomText{}
+	if err := decoder.DecodeElement(&text, &start); err != nil {
+		return err
+	}
+	*field = text.plainText()
+	return nil
+}
+
+// rss titles and descriptions are very often html escaped a second time, so they are unescaped once decoded
+func decodeEscapedText(decoder *xml.Decoder, start xml.StartElement, field *string) error {
+	if err := decoder.DecodeElement(field, &start); err != nil {
+		return err
+	}
+	*field = html.UnescapeString(*field)
+	return nil
+}
+
+// rss 1.0 elements are in their own namespace, but feeds that leave out the namespace declaration are accepted too
+func isRSS1Element(start xml.StartElement) bool {
+	return start.Name.Space == rss1Namespace || start.Name.Space == ""
+}
+
+// decoded one child at a time for the same reason as decodeRSSItem
+func decodeRDFChannel(decoder *xml.Decoder) (channel rdfChannel, err error) {
+	err = forEachChild(decoder, func(start xml.StartElement) error {
+		switch {
+		case start.Name.Space == dublinCoreNamespace && start.Name.Local == "language":
+			return decoder.DecodeElement(&channel.Language, &start)
+		case start.Name.Space == syndicationNamespace && start.Name.Local == "updatePeriod":
+			return decoder.DecodeElement(&channel.UpdatePeriod, &start)
+		case start.Name.Space == syndicationNamespace && start.Name.Local == "updateFrequency":
+			return decoder.DecodeElement(&channel.UpdateFrequency, &start)
+		case !isRSS1Element(start):
+			return decoder.Skip()
+		case start.Name.Local == "title":
+			return decoder.DecodeElement(&channel.Title, &start)
+		case start.Name.Local == "link":
+			return decoder.DecodeElement(&channel.Link, &start)
+		case start.Name.Local == "description":
+			return decoder.DecodeElement(&channel.Description, &start)
+		}
+		return decoder.Skip()
+	})
+	return channel, err
+}
+
+func decodeRDFItem(decoder *xml.Decoder, itemStart xml.StartElement) (item rdfItem, err error) {
+	for _, attr := range itemStart.Attr {
+		if attr.Name.Space == rdfNamespace && attr.Name.Local == "about" {
+			item.About = attr.Value
+		}
+	}
+	err = forEachChild(decoder, func(start xml.StartElement) error {
+		switch {
+		case start.Name.Space == dublinCoreNamespace && start.Name.Local == "date":
+			return decoder.DecodeElement(&item.Date, &start)
+		case start.Name.Space == contentNamespace && start.Name.Local == "encoded":
+			return decoder.DecodeElement(&item.Content, &start)
+		case !isRSS1Element(start):
+			return decoder.Skip()
+		case start.Name.Local == "title":
+			return decoder.DecodeElement(&item.Title, &start)
+		case start.Name.Local == "link":
+			return decoder.DecodeElement(&item.Link, &start)
+		case start.Name.Local == "description":
+			return decoder.DecodeElement(&item.Description, &start)
+		}
+		return decoder.Skip()
+	})
+	return item, err
+}
+
+func streamRDF(decoder *xml.Decoder, feed *RSSFeed, bases baseURLs, onItem itemHandler) error {
+	return forEachChild(decoder, func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "channel":
+			channel, err := decodeRDFChannel(decoder)
+			if err != nil {
+				return err
+			}
+			rdfChannelToChannel(channel, feed)
+			feed.Channel.Link = bases.setChannelLink(feed.Channel.Link)
+			return nil
+		case "image":
+			image := struct {
+				URL string `xml:"url"`
+			}{}
+			if err := decoder.DecodeElement(&image, &start); err != nil {
+				return err
+			}
+			if strings.TrimSpace(image.URL) != "" {
+				feed.Channel.ImageURL = resolveURL(bases.get(), strings.TrimSpace(image.URL))
+			}
+			return nil
+		case "item":
+			item, err := decodeRDFItem(decoder, start)
+			if err != nil {
+				return err
+			}
+			return onItem(rdfItemToItem(item), bases.withXMLBase(start))
+		}
+		return decoder.Skip()
+	})
+}
+
+// calls handle with each child element of the element that the decoder has just read the start of, returning once
+// its end element is reached. handle has to consume the whole child, either by decoding it or skipping it
+func forEachChild(decoder *xml.Decoder, handle func(start xml.StartElement) error) error {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		switch typedToken := token.(type) {
+		case xml.StartElement:
+			if err := handle(typedToken); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// reads tokens until the first element is found, so that the format can be decided before the rest is decoded
+func nextStartElement(decoder *xml.Decoder) (start xml.StartElement, err error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if startElement, ok := token.(xml.StartElement); ok {
+			return startElement, nil
+		}
+	}
+}
+
+func getXMLLang(start xml.StartElement) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == xmlNamespace && attr.Name.Local == "lang" {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func getAttrValue(start xml.StartElement, local string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
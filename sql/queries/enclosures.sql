@@ -0,0 +1,33 @@
+-- name: UpsertEnclosure :one
+INSERT INTO enclosures (
+    id,
+    created_at,
+    updated_at,
+    post_id,
+    url,
+    mime_type,
+    length_bytes,
+    duration_seconds)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (post_id, url) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    mime_type = EXCLUDED.mime_type,
+    length_bytes = EXCLUDED.length_bytes,
+    duration_seconds = EXCLUDED.duration_seconds
+RETURNING *;
+
+-- name: GetEnclosuresForPost :many
+SELECT *
+  FROM enclosures
+ WHERE post_id = $1
+ ORDER BY created_at, url;
+
+-- name: GetEnclosuresForPostURL :many
+SELECT
+    enclosures.*,
+    posts.title AS post_title
+  FROM enclosures
+  JOIN posts
+    ON enclosures.post_id = posts.id
+ WHERE posts.url = $1
+ ORDER BY posts.published_at DESC, enclosures.created_at, enclosures.url;
@@ -1,6 +1,7 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
-VALUES($1, $2, $3, $4, $5, $6)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, site_link, description, language, image_url, generator)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (url) DO NOTHING
 RETURNING *;
 
 
@@ -9,7 +10,8 @@ DELETE from feeds;
 
 
 -- name: GetFeeds :many
-SELECT feeds.name AS FeedName, feeds.url AS FeedUrl, users.name as UserName
+SELECT feeds.name AS FeedName, feeds.url AS FeedUrl, users.name as UserName,
+       feeds.site_link, feeds.description, feeds.language, feeds.image_url, feeds.generator
   FROM feeds
   JOIN users
     ON feeds.user_id = users.id;
@@ -63,9 +65,101 @@ UPDATE feeds
 SET updated_at = $1, last_fetched_at = $1
 WHERE feeds.id = $2;
 
--- name: GetNextFeedToFetch :one
-SELECT feeds.*
-  FROM feeds
-  ORDER BY feeds.last_fetched_at NULLS FIRST
-  LIMIT 1;
+-- name: ClaimNextFeedsToFetch :many
+UPDATE feeds
+SET updated_at = $1, last_fetched_at = $1, next_fetch_at = $3
+WHERE feeds.id IN (
+    SELECT feeds.id
+      FROM feeds
+     WHERE feeds.disabled_at IS NULL
+       AND (feeds.next_eligible_at IS NULL OR feeds.next_eligible_at <= $1)
+       AND (feeds.next_allowed_fetch_at IS NULL OR feeds.next_allowed_fetch_at <= $1)
+       AND (feeds.next_fetch_at IS NULL OR feeds.next_fetch_at <= $1)
+     ORDER BY feeds.next_fetch_at NULLS FIRST
+     LIMIT $2
+       FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
  
+
+-- name: UpdateFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $1, last_modified = $2, updated_at = $3
+WHERE feeds.id = $4;
+
+-- name: RecordFeedFetchSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    consecutive_not_found = 0,
+    last_error = NULL,
+    last_http_status = $1,
+    next_eligible_at = NULL,
+    updated_at = $2
+WHERE feeds.id = $3;
+
+-- name: RecordFeedFetchFailure :exec
+UPDATE feeds
+SET consecutive_failures = feeds.consecutive_failures + 1,
+    consecutive_not_found = CASE WHEN $2 = 404 THEN feeds.consecutive_not_found + 1 ELSE 0 END,
+    last_error = $1,
+    last_http_status = $2,
+    next_eligible_at = $3,
+    next_fetch_at = $3,
+    updated_at = $4
+WHERE feeds.id = $5;
+
+-- name: GetFeedsWithErrors :many
+SELECT feeds.name AS feed_name,
+       feeds.url AS feed_url,
+       feeds.consecutive_failures,
+       feeds.last_error,
+       feeds.last_http_status,
+       feeds.last_fetched_at,
+       feeds.next_eligible_at,
+       feeds.disabled_at,
+       feeds.disabled_reason
+  FROM feeds
+ WHERE feeds.consecutive_failures > 0
+    OR feeds.disabled_at IS NOT NULL
+ ORDER BY feeds.consecutive_failures DESC, feeds.name;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $1, updated_at = $2
+WHERE feeds.id = $3;
+
+-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = $1, disabled_reason = $2, updated_at = $3
+WHERE feeds.id = $4;
+
+-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled_at = NULL,
+    disabled_reason = NULL,
+    consecutive_failures = 0,
+    consecutive_not_found = 0,
+    next_eligible_at = NULL,
+    updated_at = $1
+WHERE feeds.id = $2;
+
+-- name: UpdateFeedRefreshHints :exec
+UPDATE feeds
+SET ttl_minutes = $1,
+    skip_hours = $2,
+    skip_days = $3,
+    sy_update_period = $4,
+    sy_update_frequency = $5,
+    next_allowed_fetch_at = $6,
+    updated_at = $7
+WHERE feeds.id = $8;
+
+-- name: SetFeedNextAllowedFetch :exec
+UPDATE feeds
+SET next_allowed_fetch_at = $1, updated_at = $2
+WHERE feeds.id = $3;
+
+-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = $1, updated_at = $2
+WHERE feeds.id = $3;
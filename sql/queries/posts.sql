@@ -1,4 +1,4 @@
--- name: CreatePost :one
+-- name: UpsertPost :one
 INSERT INTO posts (
     id, 
     created_at, 
@@ -7,15 +7,26 @@ INSERT INTO posts (
     url, 
     description,
     published_at,
-    feed_id)
-VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+    feed_id,
+    guid,
+    content)
+VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    published_at = EXCLUDED.published_at,
+    content = EXCLUDED.content
 RETURNING *;
 
 -- name: GetPostsForUser :many
 SELECT 
+    posts.id AS post_id,
     posts.title AS post_title,
     posts.url AS post_url,
     posts.description AS post_description,
+    posts.content AS post_content,
     posts.published_at AS post_published_on,
     feeds.name AS feed_name
   FROM users
@@ -28,3 +39,20 @@ SELECT
  WHERE users.id = $1
  ORDER BY posts.published_at DESC
   LIMIT $2;
+
+-- name: GetAveragePostInterval :one
+SELECT CAST(
+    COALESCE(
+        EXTRACT(EPOCH FROM MAX(recent_posts.published_at) - MIN(recent_posts.published_at))
+            / NULLIF(COUNT(*) - 1, 0),
+        0
+    ) AS DOUBLE PRECISION
+) AS average_interval_seconds
+  FROM (
+    SELECT posts.published_at
+      FROM posts
+     WHERE posts.feed_id = $1
+       AND posts.published_at IS NOT NULL
+     ORDER BY posts.published_at DESC
+     LIMIT 20
+  ) AS recent_posts;
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE posts
+ADD guid TEXT;
+
+UPDATE posts
+SET guid = url;
+
+ALTER TABLE posts
+ALTER COLUMN guid SET NOT NULL;
+
+ALTER TABLE posts
+DROP CONSTRAINT posts_url_key;
+
+ALTER TABLE posts
+ADD CONSTRAINT feed_id_to_guid UNIQUE (feed_id, guid);
+
+-- +goose Down
+ALTER TABLE posts
+DROP CONSTRAINT feed_id_to_guid;
+
+ALTER TABLE posts
+DROP COLUMN guid;
+
+ALTER TABLE posts
+ADD CONSTRAINT posts_url_key UNIQUE (url);
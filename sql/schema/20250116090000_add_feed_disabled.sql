@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD consecutive_not_found INTEGER NOT NULL DEFAULT 0,
+ADD disabled_at TIMESTAMP,
+ADD disabled_reason TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN consecutive_not_found,
+DROP COLUMN disabled_at,
+DROP COLUMN disabled_reason;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD ttl_minutes INTEGER,
+ADD skip_hours TEXT,
+ADD skip_days TEXT,
+ADD sy_update_period TEXT,
+ADD sy_update_frequency INTEGER,
+ADD next_allowed_fetch_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN ttl_minutes,
+DROP COLUMN skip_hours,
+DROP COLUMN skip_days,
+DROP COLUMN sy_update_period,
+DROP COLUMN sy_update_frequency,
+DROP COLUMN next_allowed_fetch_at;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE enclosures (
+    id uuid PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    post_id uuid NOT NULL,
+    url TEXT NOT NULL,
+    mime_type TEXT,
+    length_bytes BIGINT,
+    duration_seconds INTEGER,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    CONSTRAINT post_id_to_enclosure_url UNIQUE (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE enclosures;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE posts
+ADD content TEXT;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN content;